@@ -0,0 +1,117 @@
+package ggo
+
+// Enable activates every entry stored under name. It reports whether the
+// key exists.
+func (f *Config) Enable(name string) bool {
+	return f.setActive(name, true)
+}
+
+// Disable comments out every entry stored under name. It reports whether
+// the key exists.
+func (f *Config) Disable(name string) bool {
+	return f.setActive(name, false)
+}
+
+// EnableValue activates the entry of name holding value. It reports
+// whether such an entry exists.
+func (f *Config) EnableValue(name string, value string) bool {
+	return f.setValueActive(name, value, true)
+}
+
+// DisableValue comments out the entry of name holding value. It reports
+// whether such an entry exists.
+func (f *Config) DisableValue(name string, value string) bool {
+	return f.setValueActive(name, value, false)
+}
+
+func (f *Config) setActive(name string, active bool) bool {
+	name = f.canonicalName(name)
+	before := f.snapshot(name)
+	switch v := f.fields[name].(type) {
+	case *ConfigEntry:
+		v.IsActive = active
+	case *ConfigMultiEntry:
+		for _, e := range v.Entries {
+			e.IsActive = active
+		}
+	default:
+		return false
+	}
+	f.record(activeOp(active), name, before)
+	return true
+}
+
+func (f *Config) setValueActive(name string, value string, active bool) bool {
+	name = f.canonicalName(name)
+	var e *ConfigEntry
+	switch v := f.fields[name].(type) {
+	case *ConfigEntry:
+		if v.Value == value {
+			e = v
+		}
+	case *ConfigMultiEntry:
+		e = v.Get(value)
+	}
+
+	if e == nil {
+		return false
+	}
+	before := f.snapshot(name)
+	e.IsActive = active
+	f.record(activeOp(active), name, before)
+	return true
+}
+
+// activeOp names the journaled change of enabling or disabling entries.
+func activeOp(active bool) string {
+	if active {
+		return "enable"
+	}
+	return "disable"
+}
+
+// Lookup returns the value of name. The value of an active entry is
+// returned with isSet true. Otherwise a commented-out entry, or failing
+// that the schema, provides the documented default, returned with isSet
+// false. found is false when there is no value at all. For multi-valued
+// keys the first value in sorted order is used; see LookupValues.
+func (f *Config) Lookup(name string) (value string, isSet bool, found bool) {
+	values, isSet := f.LookupValues(name)
+	if len(values) == 0 {
+		return "", false, false
+	}
+	return values[0], isSet, true
+}
+
+// LookupValues is like Lookup but returns every value of a multi-valued
+// key: the active ones if there are any, or else the commented-out ones.
+func (f *Config) LookupValues(name string) (values []string, isSet bool) {
+	var active, inactive []string
+	switch v := f.Get(name).(type) {
+	case *ConfigEntry:
+		if v.IsActive {
+			active = append(active, v.Value)
+		} else {
+			inactive = append(inactive, v.Value)
+		}
+	case *ConfigMultiEntry:
+		for _, value := range v.sortedValues() {
+			if v.Entries[value].IsActive {
+				active = append(active, value)
+			} else {
+				inactive = append(inactive, value)
+			}
+		}
+	}
+
+	if len(active) > 0 {
+		return active, true
+	}
+	if len(inactive) > 0 {
+		return inactive, false
+	}
+	if k := f.schema.Lookup(name); k != nil && k.Default != "" {
+		return []string{k.Default}, false
+	}
+	return nil, false
+}
@@ -0,0 +1,99 @@
+package ggo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfig_EnableDisable(t *testing.T) {
+	testData := []string{
+		"#sflow.drop.pool 0",
+		"pcap-speed 220",
+		"sync 239.0.0.3",
+		"#sync 239.1.0.3",
+	}
+
+	file := NewConfig()
+	file.SetKeyMultiple("sync", true)
+	file.FromStrings(testData)
+
+	if !file.Enable("sflow.drop.pool") || !file.Disable("pcap-speed") {
+		t.Error("Enable/Disable failed on existing keys")
+	}
+	if file.Enable("missing") || file.EnableValue("sync", "239.2.0.3") {
+		t.Error("Enable succeeded on missing key")
+	}
+	if !file.EnableValue("sync", "239.1.0.3") || !file.DisableValue("sync", "239.0.0.3") {
+		t.Error("EnableValue/DisableValue failed on existing values")
+	}
+
+	file.checkEntry(t, true, "sflow.drop.pool", "0", "")
+	file.checkEntry(t, false, "pcap-speed", "220", "")
+	file.checkMultiEntry(t, "sync", map[string]bool{"239.0.0.3": false, "239.1.0.3": true})
+}
+
+func TestConfig_EnableDisableUndo(t *testing.T) {
+	file := NewConfig()
+	file.SetKeyMultiple("sync", true)
+	file.FromStrings([]string{"pcap-speed 220", "sync 239.0.0.3", "#sync 239.1.0.3"})
+	file.EnableJournal()
+
+	file.Disable("pcap-speed")
+	file.EnableValue("sync", "239.1.0.3")
+	if ops := file.Journal().Changes(); len(ops) != 2 || ops[0].Op != "disable" || ops[1].Op != "enable" {
+		t.Errorf("unexpected changes %+v\n", ops)
+	}
+	file.Undo()
+	file.Undo()
+	if file.Replace(nil) {
+		t.Error("Replace replaced a nil entry")
+	}
+
+	file.checkEntry(t, true, "pcap-speed", "220", "")
+	file.checkMultiEntry(t, "sync", map[string]bool{"239.0.0.3": true, "239.1.0.3": false})
+}
+
+func TestConfig_Lookup(t *testing.T) {
+	testData := []string{
+		"#sflow.drop.pool 0",
+		"pcap-speed 220",
+		"sync 239.0.0.3",
+		"#sync 239.1.0.3",
+		"#sync-neighbour 198.18.1.1",
+		"#sync-neighbour 198.18.1.3",
+	}
+
+	file := NewConfig()
+	file.SetSchema(NewSchema(
+		&KeySpec{Name: "sflow.*.pool"},
+		&KeySpec{Name: "sflow.*.rate", Default: "1000"},
+		&KeySpec{Name: "sync", Multiple: true},
+		&KeySpec{Name: "sync-neighbour", Multiple: true},
+	))
+	file.FromStrings(testData)
+
+	tests := []struct {
+		name  string
+		value string
+		isSet bool
+		found bool
+	}{
+		{"pcap-speed", "220", true, true},
+		{"sflow.drop.pool", "0", false, true},
+		{"sflow.drop.rate", "1000", false, true},
+		{"missing", "", false, false},
+	}
+	for _, test := range tests {
+		value, isSet, found := file.Lookup(test.name)
+		if value != test.value || isSet != test.isSet || found != test.found {
+			t.Errorf("Lookup(%s) = %s, %v, %v\n", test.name, value, isSet, found)
+		}
+	}
+
+	if values, isSet := file.LookupValues("sync"); !isSet || !reflect.DeepEqual(values, []string{"239.0.0.3"}) {
+		t.Errorf("LookupValues(sync) = %v, %v\n", values, isSet)
+	}
+	if values, isSet := file.LookupValues("sync-neighbour"); isSet || !reflect.DeepEqual(values, []string{"198.18.1.1", "198.18.1.3"}) {
+		t.Errorf("LookupValues(sync-neighbour) = %v, %v\n", values, isSet)
+	}
+}
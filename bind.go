@@ -0,0 +1,184 @@
+package ggo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+)
+
+var (
+	addrType   = reflect.TypeOf(netip.Addr{})
+	prefixType = reflect.TypeOf(netip.Prefix{})
+	macType    = reflect.TypeOf(net.HardwareAddr{})
+)
+
+// Bind fills the fields of the struct pointed to by v from the config.
+// Fields are matched by a `ggo:"key"` tag; untagged fields are skipped. A
+// tagged struct field binds its own fields under the tag as a prefix.
+// Slice fields receive every value of a multi-valued key. Values are
+// looked up like Lookup does, so commented-out defaults apply; keys with
+// no value leave their field untouched. References held by secret keys
+// are resolved as Resolve does.
+//
+// Supported field types are strings, booleans, integers, netip.Addr,
+// netip.Prefix and net.HardwareAddr. A schema type, when declared for the
+// key, parses the value; its result must be convertible to the field.
+func (f *Config) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("Bind requires a pointer to a struct")
+	}
+	return f.bindStruct(rv.Elem(), "")
+}
+
+func (f *Config) bindStruct(rv reflect.Value, prefix string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, tagged := field.Tag.Lookup("ggo")
+		if !tagged || name == "" || name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Struct && fv.Type() != addrType && fv.Type() != prefixType {
+			if err := f.bindStruct(fv, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		values, _ := f.LookupValues(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		if fv.Kind() == reflect.Slice && fv.Type() != macType {
+			slice := reflect.MakeSlice(fv.Type(), 0, len(values))
+			for _, value := range values {
+				elem, err := f.parseValue(name, value, fv.Type().Elem())
+				if err != nil {
+					return err
+				}
+				slice = reflect.Append(slice, elem)
+			}
+			fv.Set(slice)
+			continue
+		}
+
+		elem, err := f.parseValue(name, values[0], fv.Type())
+		if err != nil {
+			return err
+		}
+		fv.Set(elem)
+	}
+	return nil
+}
+
+// parseValue converts a value of key name to t.
+func (f *Config) parseValue(name string, value string, t reflect.Type) (reflect.Value, error) {
+	value, err := f.resolveValue(name, value)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if spec := f.schema.Lookup(name); spec != nil && spec.Type != nil {
+		parsed, err := spec.Type.Parse(value)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%s: %w", name, err)
+		}
+		pv := reflect.ValueOf(parsed)
+		if pv.Type() == t {
+			return pv, nil
+		}
+		if isNumeric(pv.Kind()) && isNumeric(t.Kind()) {
+			res, ok := convertNumber(pv, t)
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("%s: %v is out of range for %s", name, pv, t)
+			}
+			return res, nil
+		}
+		value = spec.Type.Format(parsed)
+	}
+
+	var parsed interface{}
+	switch {
+	case t == addrType:
+		parsed, err = netip.ParseAddr(value)
+	case t == prefixType:
+		parsed, err = netip.ParsePrefix(value)
+	case t == macType:
+		parsed, err = net.ParseMAC(unquote(value))
+	default:
+		switch t.Kind() {
+		case reflect.String:
+			parsed = value
+		case reflect.Bool:
+			// A key written without a value is a flag.
+			if value == "" {
+				value = "true"
+			}
+			parsed, err = strconv.ParseBool(value)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			parsed, err = strconv.ParseInt(value, 10, t.Bits())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			parsed, err = strconv.ParseUint(value, 10, t.Bits())
+		default:
+			err = fmt.Errorf("unsupported field type %s", t)
+		}
+	}
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return reflect.ValueOf(parsed).Convert(t), nil
+}
+
+func isNumeric(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Uint64
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uint64
+}
+
+// convertNumber converts the number v to t. It reports false instead of
+// truncating or wrapping around values t cannot hold.
+func convertNumber(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	res := reflect.New(t).Elem()
+	if isUnsigned(v.Kind()) {
+		n := v.Uint()
+		if isUnsigned(t.Kind()) {
+			if res.OverflowUint(n) {
+				return res, false
+			}
+			res.SetUint(n)
+		} else {
+			if n > math.MaxInt64 || res.OverflowInt(int64(n)) {
+				return res, false
+			}
+			res.SetInt(int64(n))
+		}
+		return res, true
+	}
+
+	n := v.Int()
+	if isUnsigned(t.Kind()) {
+		if n < 0 || res.OverflowUint(uint64(n)) {
+			return res, false
+		}
+		res.SetUint(uint64(n))
+	} else {
+		if res.OverflowInt(n) {
+			return res, false
+		}
+		res.SetInt(n)
+	}
+	return res, true
+}
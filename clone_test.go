@@ -0,0 +1,77 @@
+package ggo
+
+import "testing"
+
+func cloneSource() *Config {
+	c := NewConfig()
+	c.SetSchema(NewSchema(
+		&KeySpec{Name: "vlan", Aliases: []string{"vlan-id"}},
+		&KeySpec{Name: "sync", Multiple: true},
+	))
+	c.FromString("# access vlan\nvlan 10 # edge\nsync 10.0.0.1\n# sync 10.0.0.2")
+	return c
+}
+
+func Test_MultiEntryCopy(t *testing.T) {
+	c := cloneSource()
+	m := c.Get("sync").(*ConfigMultiEntry)
+	cp := m.Copy().(*ConfigMultiEntry)
+
+	if cp.Name() != "sync" {
+		t.Errorf("copy named '%s'\n", cp.Name())
+	}
+	cp.Get("10.0.0.1").IsActive = false
+	cp.Delete("10.0.0.2")
+	if !m.Get("10.0.0.1").IsActive || m.Get("10.0.0.2") == nil {
+		t.Errorf("copy shares entries with the original\n")
+	}
+}
+
+func Test_Clone(t *testing.T) {
+	c := cloneSource()
+	cp := c.Clone()
+	if cp.String() != c.String() {
+		t.Errorf("clone differs:\n%s\n%s\n", cp.String(), c.String())
+	}
+	if e := cp.Get("vlan").(*ConfigEntry); e.DocComment != "access vlan" || e.Comment != "edge" || e.Line() != 2 {
+		t.Errorf("clone lost comments or provenance: %+v\n", e)
+	}
+	if cp.Get("vlan-id") == nil || !cp.isMultiple("sync") {
+		t.Errorf("clone lost its schema\n")
+	}
+
+	cp.Get("vlan").(*ConfigEntry).Value = "20"
+	cp.Get("sync").(*ConfigMultiEntry).Get("10.0.0.1").Comment = "changed"
+	cp.Set(ParseString("mtu 9000"))
+	cp.SetKeyMultiple("mtu", true)
+	cp.Schema().Add(&KeySpec{Name: "mtu"})
+	cp.Schema().Lookup("vlan").Aliases[0] = "changed"
+
+	c.checkEntry(t, true, "vlan", "10", "edge")
+	if e := c.Get("sync").(*ConfigMultiEntry).Get("10.0.0.1"); e.Comment != "" {
+		t.Errorf("clone shares multi-valued entries\n")
+	}
+	if c.Get("mtu") != nil || c.isMultiple("mtu") || c.Schema().Lookup("mtu") != nil {
+		t.Errorf("clone shares keys, settings or schema\n")
+	}
+	if c.Schema().Lookup("vlan").Aliases[0] != "vlan-id" {
+		t.Errorf("clone shares key specs\n")
+	}
+}
+
+func Test_MergeDoesNotAlias(t *testing.T) {
+	a, b := cloneSource(), NewConfig()
+	b.FromString("vlan 20\nsync 10.0.0.3")
+	merged := Merge(a, b)
+
+	merged.Get("vlan").(*ConfigEntry).Value = "30"
+	for _, e := range merged.Get("sync").(*ConfigMultiEntry).Entries {
+		e.Value = "changed"
+	}
+
+	b.checkEntry(t, true, "vlan", "20", "")
+	b.checkEntry(t, true, "sync", "10.0.0.3", "")
+	if a.Get("sync").(*ConfigMultiEntry).Get("10.0.0.1").Value != "10.0.0.1" {
+		t.Errorf("merge shares multi-valued entries\n")
+	}
+}
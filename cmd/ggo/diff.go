@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unifiedDiff returns a unified diff between two texts, or an empty string
+// if they are equal.
+func unifiedDiff(name string, a string, b string) string {
+	if a == b {
+		return ""
+	}
+	x := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	y := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type op struct {
+		kind byte
+		text string
+		i, j int
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, op{' ', x[i], i, j})
+			i++
+			j++
+		case j < len(y) && (i == len(x) || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, op{'+', y[j], i, j})
+			j++
+		default:
+			ops = append(ops, op{'-', x[i], i, j})
+			i++
+		}
+	}
+
+	const context = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		to := start
+		for k := start; k < len(ops) && k <= to+2*context; k++ {
+			if ops[k].kind != ' ' {
+				to = k
+			}
+		}
+		to += context + 1
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		ai, bj, an, bn := ops[from].i, ops[from].j, 0, 0
+		for _, o := range ops[from:to] {
+			if o.kind != '+' {
+				an++
+			}
+			if o.kind != '-' {
+				bn++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", ai+1, an, bj+1, bn)
+		for _, o := range ops[from:to] {
+			fmt.Fprintf(&out, "%c%s\n", o.kind, o.text)
+		}
+		start = to
+	}
+	return out.String()
+}
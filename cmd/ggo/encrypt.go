@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	ggo "github.com/SPROgster/ggo_config"
+)
+
+func runEncryptValue(args []string) int {
+	fs := flag.NewFlagSet("encrypt-value", flag.ExitOnError)
+	keyFile := fs.String("key", "", "file holding the secret key, raw or base64")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: ggo encrypt-value -key file [value...]")
+		fmt.Fprintln(os.Stderr, "Values are read from stdin, one per line, when none are given.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *keyFile == "" {
+		fs.Usage()
+		return 2
+	}
+	data, err := os.ReadFile(*keyFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ggo encrypt-value:", err)
+		return 2
+	}
+	key, err := ggo.ParseSecretKey(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ggo encrypt-value: %s: %v\n", *keyFile, err)
+		return 2
+	}
+
+	values := fs.Args()
+	if len(values) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			values = append(values, strings.TrimRight(scanner.Text(), "\r"))
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintln(os.Stderr, "ggo encrypt-value:", err)
+			return 1
+		}
+	}
+
+	for _, value := range values {
+		enc, err := ggo.EncryptValue(key, value)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ggo encrypt-value:", err)
+			return 1
+		}
+		fmt.Println(enc)
+	}
+	return 0
+}
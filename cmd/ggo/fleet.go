@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	ggo "github.com/SPROgster/ggo_config"
+)
+
+// fleetOp is an edit applied to every file: set, unset, add or remove,
+// with the entry it applies to.
+type fleetOp struct {
+	name  string
+	entry *ggo.ConfigEntry
+}
+
+func parseFleetOp(name string, arg string) (fleetOp, error) {
+	e := ggo.ParseString(arg)
+	if e == nil || !e.IsActive {
+		return fleetOp{}, fmt.Errorf("%s: invalid entry '%s'", name, arg)
+	}
+	if (name == "add" || name == "remove") && e.Value == "" {
+		return fleetOp{}, fmt.Errorf("%s: no value for %s", name, e.Name())
+	}
+	return fleetOp{name: name, entry: e}, nil
+}
+
+// fleetOps collects edits in command line order; each flag adds its own
+// kind of edit.
+type fleetOps struct {
+	name string
+	ops  *[]fleetOp
+}
+
+func (f fleetOps) String() string {
+	return ""
+}
+
+func (f fleetOps) Set(arg string) error {
+	op, err := parseFleetOp(f.name, arg)
+	if err != nil {
+		return err
+	}
+	*f.ops = append(*f.ops, op)
+	return nil
+}
+
+// readPatch reads edits from a file of `op key [value]` lines. Empty lines
+// and lines starting with '#' are skipped.
+func readPatch(name string) ([]fleetOp, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []fleetOp
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		switch fields[0] {
+		case "set", "unset", "add", "remove":
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown operation '%s'", name, i+1, fields[0])
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: no key", name, i+1)
+		}
+		op, err := parseFleetOp(fields[0], fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, i+1, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// apply edits c. set replaces every value of the key, add adds a value to
+// a multi-valued key.
+func (op fleetOp) apply(c *ggo.Config) error {
+	name := op.entry.Name()
+	switch op.name {
+	case "set":
+		return c.SetAll(name, op.entry.Copy().(*ggo.ConfigEntry))
+	case "unset":
+		c.Delete(name)
+	case "add":
+		if err := c.Add(op.entry.Copy().(*ggo.ConfigEntry)); err != nil {
+			return fmt.Errorf("cannot add to %v", err)
+		}
+	case "remove":
+		c.DeleteValue(name, op.entry.Value)
+	}
+	return nil
+}
+
+// fleetFile is a file being edited.
+type fleetFile struct {
+	name   string
+	before []byte
+	after  []byte
+	mode   os.FileMode
+	tmp    string
+	// diff shows the edits on the redacted renderings of the file.
+	diff string
+}
+
+func expandGlobs(patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+	for _, p := range patterns {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match '%s'", p)
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				res = append(res, m)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+func runFleet(args []string) int {
+	fs := flag.NewFlagSet("fleet", flag.ExitOnError)
+	schemeOpts := addSchemeFlags(fs)
+	var ops []fleetOp
+	fs.Var(fleetOps{"set", &ops}, "set", "set `\"key value\"`, replacing every value of the key")
+	fs.Var(fleetOps{"unset", &ops}, "unset", "remove `key` entirely")
+	fs.Var(fleetOps{"add", &ops}, "add", "add `\"key value\"` to a multi-valued key")
+	fs.Var(fleetOps{"remove", &ops}, "remove", "remove the value of `\"key value\"`")
+	patch := fs.String("patch", "", "file of `set|unset|add|remove key [value]` lines applied first")
+	dryRun := fs.Bool("n", false, "print the diff of every file instead of writing")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: ggo fleet [flags] glob...")
+		fmt.Fprintln(os.Stderr, "Edits every matching file. Files are only written if all of them")
+		fmt.Fprintln(os.Stderr, "pass validation.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *patch != "" {
+		patchOps, err := readPatch(*patch)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ggo fleet:", err)
+			return 2
+		}
+		ops = append(patchOps, ops...)
+	}
+	if len(ops) == 0 || fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	scheme, err := schemeOpts.scheme()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ggo fleet:", err)
+		return 2
+	}
+	names, err := expandGlobs(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ggo fleet:", err)
+		return 2
+	}
+
+	files := make([]*fleetFile, 0, len(names))
+	failed := false
+	for _, name := range names {
+		f, err := editFile(name, scheme, ops)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ggo fleet: %s: %v\n", name, err)
+			failed = true
+			continue
+		}
+		if *dryRun {
+			fmt.Print(f.diff)
+		}
+		if !bytes.Equal(f.before, f.after) {
+			files = append(files, f)
+		}
+	}
+
+	if failed {
+		if !*dryRun {
+			fmt.Fprintln(os.Stderr, "ggo fleet: no files written")
+		}
+		return 1
+	}
+	if *dryRun {
+		return 0
+	}
+	if err := writeAll(files); err != nil {
+		fmt.Fprintln(os.Stderr, "ggo fleet:", err)
+		return 2
+	}
+	return 0
+}
+
+// editFile reads a file, applies ops and validates the result. The file
+// is edited in place, keeping its layout and comments.
+func editFile(name string, scheme *ggo.Config, ops []fleetOp) (*fleetFile, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	f := &fleetFile{name: name, mode: info.Mode().Perm()}
+	if f.before, err = io.ReadAll(file); err != nil {
+		return nil, err
+	}
+
+	c := scheme.CopyScheme()
+	if err := c.ParseConfig(f.before); err != nil {
+		return nil, err
+	}
+	orig := c.Clone()
+	for _, op := range ops {
+		if err := op.apply(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	if f.after, err = ggo.PatchDocument(f.before, c); err != nil {
+		return nil, err
+	}
+	f.diff = unifiedDiff(name, orig.String(), c.String())
+	return f, nil
+}
+
+// writeAll writes every file to a temporary file next to it, and only
+// once all of them are written renames them over the originals.
+func writeAll(files []*fleetFile) error {
+	defer func() {
+		for _, f := range files {
+			if f.tmp != "" {
+				os.Remove(f.tmp)
+			}
+		}
+	}()
+
+	for _, f := range files {
+		tmp, err := os.CreateTemp(filepath.Dir(f.name), "."+filepath.Base(f.name)+".*")
+		if err != nil {
+			return err
+		}
+		f.tmp = tmp.Name()
+		_, err = tmp.Write(f.after)
+		if err == nil {
+			err = tmp.Chmod(f.mode)
+		}
+		if err == nil {
+			err = tmp.Sync()
+		}
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v; no files written", f.name, err)
+		}
+	}
+
+	var errs []error
+	for i, f := range files {
+		if err := os.Rename(f.tmp, f.name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", f.name, err))
+			continue
+		}
+		files[i].tmp = ""
+	}
+	return errors.Join(errs...)
+}
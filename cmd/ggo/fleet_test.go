@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ggo "github.com/SPROgster/ggo_config"
+)
+
+func writeTestFile(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadPatch(t *testing.T) {
+	dir := t.TempDir()
+	name := writeTestFile(t, dir, "patch", "# rollout\n"+
+		"set vlan 20\n"+
+		"\n"+
+		"unset speed\n"+
+		"add vrf red\n"+
+		"remove vrf blue\n")
+
+	ops, err := readPatch(name)
+	if err != nil {
+		t.Fatalf("readPatch failed: %v\n", err)
+	}
+	expected := []string{"set vlan 20", "unset speed", "add vrf red", "remove vrf blue"}
+	if len(ops) != len(expected) {
+		t.Fatalf("unexpected ops %v\n", ops)
+	}
+	for i, op := range ops {
+		if got := op.name + " " + op.entry.String(); got != expected[i] {
+			t.Errorf("op %d is '%s', expected '%s'\n", i, got, expected[i])
+		}
+	}
+
+	for _, bad := range []string{"rename vlan 20\n", "set\n", "add vrf\n"} {
+		name := writeTestFile(t, dir, "bad", bad)
+		if _, err := readPatch(name); err == nil || !strings.HasPrefix(err.Error(), name+":1:") {
+			t.Errorf("readPatch accepted '%s': %v\n", strings.TrimSpace(bad), err)
+		}
+	}
+}
+
+func TestEditFile(t *testing.T) {
+	dir := t.TempDir()
+	name := writeTestFile(t, dir, "a.conf", "# switch a\n"+
+		"vlan 10\n"+
+		"password hunter2\n"+
+		"\n"+
+		"[tb.sym]\n"+
+		"mtu 1500\n")
+
+	scheme := ggo.NewConfig()
+	scheme.SetKeySecret("password", true)
+	ops := []fleetOp{
+		{"set", ggo.NewEntry("vlan", "20")},
+		{"set", ggo.NewEntry("password", "0")},
+	}
+	f, err := editFile(name, scheme, ops)
+	if err != nil {
+		t.Fatalf("editFile failed: %v\n", err)
+	}
+
+	expected := "# switch a\n" +
+		"vlan 20\n" +
+		"password 0\n" +
+		"\n" +
+		"[tb.sym]\n" +
+		"mtu 1500\n"
+	if string(f.after) != expected {
+		t.Errorf("unexpected content:\n%s\n", f.after)
+	}
+	if f.mode != 0640 {
+		t.Errorf("unexpected mode %v\n", f.mode)
+	}
+	if strings.Contains(f.diff, "hunter2") || strings.Contains(f.diff, "password 0") {
+		t.Errorf("secret values in the diff:\n%s\n", f.diff)
+	}
+	if !strings.Contains(f.diff, "+vlan 20") {
+		t.Errorf("unexpected diff:\n%s\n", f.diff)
+	}
+
+	bad := []fleetOp{{"add", ggo.NewEntry("vlan", "30")}}
+	if _, err := editFile(name, scheme, bad); err == nil {
+		t.Errorf("editFile added a value to a single-valued key\n")
+	}
+}
+
+func TestWriteAll(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a.conf", "vlan 10\n")
+	b := writeTestFile(t, dir, "b.conf", "vlan 10\n")
+
+	files := []*fleetFile{
+		{name: a, after: []byte("vlan 20\n"), mode: 0600},
+		{name: b, after: []byte("vlan 20\n"), mode: 0644},
+	}
+	if err := writeAll(files); err != nil {
+		t.Fatalf("writeAll failed: %v\n", err)
+	}
+	for _, f := range files {
+		data, _ := os.ReadFile(f.name)
+		info, _ := os.Stat(f.name)
+		if string(data) != "vlan 20\n" || info.Mode().Perm() != f.mode {
+			t.Errorf("%s: unexpected content '%s' or mode %v\n", f.name, data, info.Mode().Perm())
+		}
+	}
+
+	files = []*fleetFile{
+		{name: a, after: []byte("vlan 30\n"), mode: 0600},
+		{name: filepath.Join(dir, "missing", "c.conf"), after: []byte("vlan 30\n"), mode: 0600},
+	}
+	if err := writeAll(files); err == nil {
+		t.Errorf("writeAll succeeded writing to a missing directory\n")
+	}
+	if data, _ := os.ReadFile(a); string(data) != "vlan 20\n" {
+		t.Errorf("writeAll wrote %s although another file failed\n", a)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("temporary files left behind: %v\n", entries)
+	}
+}
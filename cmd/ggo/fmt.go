@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	ggo "github.com/SPROgster/ggo_config"
+)
+
+func runFmt(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	schemeOpts := addSchemeFlags(fs)
+	write := fs.Bool("w", false, "write the result to the file instead of stdout")
+	diff := fs.Bool("d", false, "print a diff instead of the formatted file")
+	sortKeys := fs.Bool("sort", false, "sort entries within each group")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: ggo fmt [flags] [file...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	scheme, err := schemeOpts.scheme()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ggo fmt:", err)
+		return 2
+	}
+	opts := ggo.FormatOptions{Scheme: scheme, Sort: *sortKeys}
+
+	if fs.NArg() == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ggo fmt:", err)
+			return 2
+		}
+		out, err := ggo.FormatWith(data, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ggo fmt: <stdin>:", err)
+			return 1
+		}
+		if *diff {
+			fmt.Print(unifiedDiff("<stdin>", string(data), string(out)))
+		} else {
+			os.Stdout.Write(out)
+		}
+		return 0
+	}
+
+	status := 0
+	for _, name := range fs.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ggo fmt:", err)
+			status = 2
+			continue
+		}
+		out, err := ggo.FormatWith(data, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ggo fmt: %s: %v\n", name, err)
+			status = 1
+			continue
+		}
+
+		if *diff {
+			fmt.Print(unifiedDiff(name, string(data), string(out)))
+		}
+		if *write {
+			if string(out) == string(data) {
+				continue
+			}
+			info, err := os.Stat(name)
+			if err == nil {
+				err = os.WriteFile(name, out, info.Mode())
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "ggo fmt:", err)
+				status = 2
+			}
+		}
+		if !*diff && !*write {
+			os.Stdout.Write(out)
+		}
+	}
+	return status
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	ggo "github.com/SPROgster/ggo_config"
+)
+
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	schemeOpts := addSchemeFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: ggo lint [flags] file...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	scheme, err := schemeOpts.scheme()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ggo lint:", err)
+		return 2
+	}
+
+	status := 0
+	for _, name := range fs.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ggo lint:", err)
+			status = 2
+			continue
+		}
+		for _, d := range ggo.Lint(data, scheme) {
+			if d.Key != "" {
+				fmt.Printf("%s:%d: %s: %s\n", name, d.Line, d.Key, d.Message)
+			} else {
+				fmt.Printf("%s:%d: %s\n", name, d.Line, d.Message)
+			}
+			if status == 0 {
+				status = 1
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,49 @@
+// Command ggo checks and edits ggo config files.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	run     func(args []string) int
+	summary string
+}
+
+var commands = map[string]*command{
+	"encrypt-value": {runEncryptValue, "encrypt values for secret keys"},
+	"fleet":         {runFleet, "edit many config files at once"},
+	"fmt":           {runFmt, "rewrite config files in canonical form"},
+	"lint":          {runLint, "report suspicious lines in config files"},
+	"migrate":       {runMigrate, "rename deprecated keys in config files"},
+	"query":         {runQuery, "print the entries of config files matching a query"},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: ggo <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].summary)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, exists := commands[os.Args[1]]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "ggo: unknown command '%s'\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	os.Exit(cmd.run(os.Args[2:]))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	ggo "github.com/SPROgster/ggo_config"
+)
+
+// ruleTypes are the value types a rule may convert values to.
+var ruleTypes = map[string]ggo.Type{
+	"int":      ggo.TypeInt,
+	"ip":       ggo.TypeIP,
+	"prefix":   ggo.TypePrefix,
+	"network":  ggo.TypeNetwork,
+	"vlan":     ggo.TypeVLAN,
+	"mac":      ggo.TypeMAC,
+	"rate":     ggo.TypeRate,
+	"duration": ggo.TypeDuration,
+}
+
+// readRules reads migration rules from a file where every line
+// `old.key new.key [type]` renames a key and, given a type, converts its
+// values to the canonical form of the type, as the schema's migration
+// rules do for aliases of typed keys. Text after '#' is a comment.
+func readRules(name string) ([]ggo.MigrationRule, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*ggo.KeySpec
+	for i, line := range strings.Split(string(data), "\n") {
+		if j := strings.IndexByte(line, '#'); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch len(fields) {
+		case 1:
+			return nil, fmt.Errorf("%s:%d: no new name for %s", name, i+1, fields[0])
+		case 2, 3:
+		default:
+			return nil, fmt.Errorf("%s:%d: too many fields", name, i+1)
+		}
+
+		spec := &ggo.KeySpec{Name: fields[1], Aliases: []string{fields[0]}}
+		if len(fields) == 3 {
+			t, exists := ruleTypes[fields[2]]
+			if !exists {
+				return nil, fmt.Errorf("%s:%d: unknown type '%s'", name, i+1, fields[2])
+			}
+			spec.Type = t
+		}
+		keys = append(keys, spec)
+	}
+	return ggo.NewSchema(keys...).MigrationRules(), nil
+}
+
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	schemeOpts := addSchemeFlags(fs)
+	rulesFile := fs.String("rules", "", "file of `old.key new.key [type]` lines")
+	write := fs.Bool("w", false, "write the result to the file instead of stdout")
+	diff := fs.Bool("d", false, "print a diff instead of the migrated file")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: ggo migrate -rules file [flags] file...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *rulesFile == "" {
+		fs.Usage()
+		return 2
+	}
+	rules, err := readRules(*rulesFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ggo migrate:", err)
+		return 2
+	}
+	scheme, err := schemeOpts.scheme()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ggo migrate:", err)
+		return 2
+	}
+
+	status := 0
+	for _, name := range fs.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ggo migrate:", err)
+			status = 2
+			continue
+		}
+		out, err := ggo.MigrateDocument(data, scheme, rules)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ggo migrate: %s: %v\n", name, err)
+			status = 1
+			continue
+		}
+
+		if *diff {
+			fmt.Print(unifiedDiff(name, string(data), string(out)))
+		}
+		if *write && string(out) != string(data) {
+			info, err := os.Stat(name)
+			if err == nil {
+				err = os.WriteFile(name, out, info.Mode())
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "ggo migrate:", err)
+				status = 2
+			}
+		}
+		if !*diff && !*write {
+			os.Stdout.Write(out)
+		}
+	}
+	return status
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	ggo "github.com/SPROgster/ggo_config"
+)
+
+func TestReadRules(t *testing.T) {
+	dir := t.TempDir()
+	name := writeTestFile(t, dir, "rules", "# renamed in 2.0\n"+
+		"pcap-speed pcap.speed rate # pps\n"+
+		"\n"+
+		"sync-neighbour sync.neighbour\n")
+
+	rules, err := readRules(name)
+	if err != nil || len(rules) != 2 {
+		t.Fatalf("readRules = %v, %v\n", rules, err)
+	}
+	doc := "pcap-speed 220000\nsync-neighbour 198.18.1.3\n"
+	got, err := ggo.MigrateDocument([]byte(doc), nil, rules)
+	if err != nil || string(got) != "pcap.speed 220k\nsync.neighbour 198.18.1.3\n" {
+		t.Errorf("unexpected migration (%v):\n%s\n", err, got)
+	}
+
+	for _, bad := range []string{"pcap-speed\n", "pcap-speed pcap.speed bits\n", "a b rate c\n"} {
+		name := writeTestFile(t, dir, "bad", bad)
+		if _, err := readRules(name); err == nil {
+			t.Errorf("readRules accepted '%s'\n", bad)
+		}
+	}
+}
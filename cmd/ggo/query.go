@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	ggo "github.com/SPROgster/ggo_config"
+)
+
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	schemeOpts := addSchemeFlags(fs)
+	count := fs.Bool("c", false, "print the number of matches per file instead")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: ggo query [flags] expr file...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return 2
+	}
+	q, err := ggo.ParseQuery(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ggo query:", err)
+		return 2
+	}
+	scheme, err := schemeOpts.scheme()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ggo query:", err)
+		return 2
+	}
+
+	status := 1
+	for _, name := range fs.Args()[1:] {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ggo query:", err)
+			status = 2
+			continue
+		}
+		c := scheme.CopyScheme()
+		err = c.FromFile(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ggo query: %s: %v\n", name, err)
+			status = 2
+			continue
+		}
+
+		entries := q.Select(c)
+		if *count {
+			fmt.Printf("%s: %d\n", name, len(entries))
+		} else {
+			for _, e := range entries {
+				if c.IsSecret(e.Name()) && e.Value != "" {
+					e = e.Copy().(*ggo.ConfigEntry)
+					e.Value = "<redacted>"
+				}
+				fmt.Printf("%s:%d: %s\n", name, e.Line(), e)
+			}
+		}
+		if len(entries) > 0 && status == 1 {
+			status = 0
+		}
+	}
+	return status
+}
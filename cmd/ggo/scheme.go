@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	ggo "github.com/SPROgster/ggo_config"
+)
+
+// schemeFlags describe how config files are to be read: which keys are
+// multi-valued or secret, the schema and the comment mode.
+type schemeFlags struct {
+	schema string
+	multi  string
+	secret string
+	strict bool
+}
+
+func addSchemeFlags(fs *flag.FlagSet) *schemeFlags {
+	s := new(schemeFlags)
+	fs.StringVar(&s.schema, "schema", "", "sample config declaring the known keys")
+	fs.StringVar(&s.multi, "multi", "", "comma-separated list of multi-valued keys")
+	fs.StringVar(&s.secret, "secret", "", "comma-separated list of keys whose values are redacted")
+	fs.BoolVar(&s.strict, "strict", false, "only treat #- lines as disabled entries")
+	return s
+}
+
+func (s *schemeFlags) scheme() (*ggo.Config, error) {
+	c := ggo.NewConfig()
+	for _, k := range strings.Split(s.multi, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			c.SetKeyMultiple(k, true)
+		}
+	}
+	for _, k := range strings.Split(s.secret, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			c.SetKeySecret(k, true)
+		}
+	}
+	c.SetStrictComments(s.strict)
+
+	if s.schema != "" {
+		sample := c.CopyScheme()
+		data, err := os.ReadFile(s.schema)
+		if err != nil {
+			return nil, err
+		}
+		sample.FromString(string(data))
+		c.SetSchema(ggo.SchemaFromConfig(sample))
+	}
+	return c, nil
+}
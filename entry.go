@@ -11,6 +11,12 @@ type ConfigEntry struct {
 	name     string
 	Value    string
 	Comment  string
+	// DocComment holds the comment lines directly preceding the entry,
+	// without their '#' markers, joined with "\n".
+	DocComment string
+
+	// line is the source line the entry was parsed from, 0 if unknown.
+	line int
 }
 
 var (
@@ -30,6 +36,12 @@ func (e *ConfigEntry) Name() string {
 	return e.name
 }
 
+// Line returns the number of the source line the entry was parsed from,
+// or 0 if the entry was not parsed from a document.
+func (e *ConfigEntry) Line() int {
+	return e.line
+}
+
 func removeEmpty(strs []string) []string {
 	result := make([]string, 0, len(strs))
 
@@ -57,7 +69,12 @@ func getValue(line []string) (*string, []string, error) {
 
 	for i, v := range line {
 		l := len(v)
-		if l > 2 && v[l-2:] == "\\\"" {
+		// A quote preceded by an odd number of backslashes is escaped.
+		n := 0
+		for j := l - 2; j >= 0 && v[j] == '\\'; j-- {
+			n++
+		}
+		if n%2 == 1 || i == 0 && l == 1 {
 			continue
 		}
 
@@ -125,11 +142,17 @@ func ParseString(str string) *ConfigEntry {
 }
 
 func (e *ConfigEntry) String() string {
+	return e.format(e.Name())
+}
+
+// format renders the entry under the given name, which differs from the
+// entry name when the entry is written inside a block.
+func (e *ConfigEntry) format(name string) string {
 	var res string
 	if !e.IsActive {
 		res = "# "
 	}
-	res += e.Name()
+	res += name
 	if len(e.Value) > 0 {
 		res += " " + e.Value
 	}
@@ -146,13 +169,72 @@ func (e *ConfigEntry) StringLn() string {
 }
 
 func (e *ConfigEntry) ChooseActiveOrReduce(e1 *ConfigEntry) ConfigEntryInterface {
-	if !e.IsActive {
-		return e1
+	res, other := e1, e
+	if e.IsActive && !e1.IsActive {
+		res, other = e, e1
 	}
-	if e1.IsActive {
-		return e1
+	if res.DocComment == "" {
+		res.DocComment = other.DocComment
 	}
-	return e
+	return res
+}
+
+// inheritDoc returns e, or a copy of it carrying prev's doc comment when e
+// has none of its own.
+func inheritDoc(e *ConfigEntry, prev *ConfigEntry) *ConfigEntry {
+	if prev == nil || e.DocComment != "" || prev.DocComment == "" {
+		return e
+	}
+	res := e.Copy().(*ConfigEntry)
+	res.DocComment = prev.DocComment
+	return res
+}
+
+// disabledMarker explicitly marks a commented-out entry, as opposed to a
+// prose comment. It is required in strict mode.
+const disabledMarker = "#-"
+
+// isDisabledMarked reports whether a trimmed line starts with the disabled
+// marker. Rulers such as `#-----` do not count.
+func isDisabledMarked(line string) bool {
+	return strings.HasPrefix(line, disabledMarker) && len(line) > len(disabledMarker) &&
+		line[len(disabledMarker)] != '-'
+}
+
+// isSectionHeader reports whether a trimmed line is a section header such
+// as `## TCP`. Headers are never parsed as commented-out entries.
+func isSectionHeader(line string) bool {
+	return strings.HasPrefix(line, "##")
+}
+
+// commentText strips the '#' markers off a comment line.
+func commentText(line string) string {
+	return strings.TrimSpace(strings.TrimLeft(line, "#"))
+}
+
+// commentLines renders a doc comment so that f reads it back as a comment
+// rather than as commented-out entries. All its lines take the same
+// marker: `##` if any of them would otherwise read as an entry, or `#`.
+func (f *Config) commentLines(doc string) []string {
+	texts := strings.Split(doc, "\n")
+	marker := "#"
+	for _, text := range texts {
+		e := ParseString("# " + text)
+		if !f.strict && e != nil && (f.schema == nil || f.schema.Lookup(e.Name()) != nil) {
+			marker = "##"
+			break
+		}
+	}
+
+	res := make([]string, 0, len(texts))
+	for _, text := range texts {
+		if text == "" {
+			res = append(res, marker)
+		} else {
+			res = append(res, marker+" "+text)
+		}
+	}
+	return res
 }
 
 func (e *ConfigEntry) MakeMultiple() *ConfigMultiEntry {
@@ -163,6 +245,8 @@ func (e *ConfigEntry) MakeMultiple() *ConfigMultiEntry {
 	return res
 }
 
+// Copy returns a copy of the entry, including its comments and source
+// line.
 func (e *ConfigEntry) Copy() ConfigEntryInterface {
 	res := new(ConfigEntry)
 	*res = *e
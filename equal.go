@@ -0,0 +1,126 @@
+package ggo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+type equalOptions struct {
+	ignoreComments bool
+	ignoreInactive bool
+	semantic       bool
+	lineOrder      bool
+}
+
+// EqualOption relaxes the comparison made by Equal and Config.Hash.
+type EqualOption func(o *equalOptions)
+
+// IgnoreComments ignores inline and doc comments.
+func IgnoreComments() EqualOption {
+	return func(o *equalOptions) { o.ignoreComments = true }
+}
+
+// IgnoreInactive ignores commented-out entries.
+func IgnoreInactive() EqualOption {
+	return func(o *equalOptions) { o.ignoreInactive = true }
+}
+
+// SemanticValues compares values in canonical form: unquoted and, where
+// the schema type of the key, or else an integer, rate, IP address,
+// prefix or MAC address type, parses them, formatted by that type.
+func SemanticValues() EqualOption {
+	return func(o *equalOptions) { o.semantic = true }
+}
+
+// LineOrder requires the values of multi-valued keys to appear in the
+// same order in their documents. By default they are compared as sets.
+// Written configs list values sorted, so a config read back from its own
+// output may differ from it under this option.
+func LineOrder() EqualOption {
+	return func(o *equalOptions) { o.lineOrder = true }
+}
+
+// semanticTypes are tried in order to normalize values of keys without a
+// schema type.
+var semanticTypes = []Type{TypeRate, TypeInt, TypeIP, TypePrefix, MACType{}}
+
+// Equal reports whether a and b hold the same entries. Only the entries
+// are compared, not schemas, settings or source lines.
+func Equal(a *Config, b *Config, opts ...EqualOption) bool {
+	return bytes.Equal(a.canonical(opts), b.canonical(opts))
+}
+
+// Hash returns a digest of the entries of the config, as compared by
+// Equal with the same options: configs are equal if and only if their
+// hashes are. Unless LineOrder is given, the digest does not depend on the
+// order entries were read or set in, nor, unlike String, on the layout.
+func (f *Config) Hash(opts ...EqualOption) string {
+	sum := sha256.Sum256(f.canonical(opts))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalEntry is the form entries are compared in.
+type canonicalEntry struct {
+	Key      string `json:"k"`
+	Value    string `json:"v"`
+	Inactive bool   `json:"i,omitempty"`
+	Comment  string `json:"c,omitempty"`
+	Doc      string `json:"d,omitempty"`
+
+	line int
+}
+
+// canonical encodes the entries of f as compared with opts.
+func (f *Config) canonical(opts []EqualOption) []byte {
+	var o equalOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var res []canonicalEntry
+	for _, k := range f.sortedKeys() {
+		start := len(res)
+		for _, e := range entriesOf(f.fields[k]) {
+			if o.ignoreInactive && !e.IsActive {
+				continue
+			}
+			c := canonicalEntry{Key: k, Value: e.Value, Inactive: !e.IsActive, line: e.line}
+			if o.semantic {
+				c.Value = f.semanticValue(k, e.Value)
+			}
+			if !o.ignoreComments {
+				c.Comment, c.Doc = e.Comment, e.DocComment
+			}
+			res = append(res, c)
+		}
+		// Values are sorted; by line first with LineOrder, where entries
+		// set rather than read have no position and come last.
+		group := res[start:]
+		sort.SliceStable(group, func(i, j int) bool {
+			if a, b := group[i].line, group[j].line; o.lineOrder && a != b {
+				return a != 0 && (b == 0 || a < b)
+			}
+			return group[i].Value < group[j].Value
+		})
+	}
+
+	data, _ := json.Marshal(res)
+	return data
+}
+
+func (f *Config) semanticValue(name string, value string) string {
+	value = unquote(value)
+	types := semanticTypes
+	if spec := f.schema.Lookup(name); spec != nil && spec.Type != nil {
+		types = append([]Type{spec.Type}, types...)
+	}
+	for _, t := range types {
+		if parsed, err := t.Parse(value); err == nil {
+			return unquote(t.Format(parsed))
+		}
+	}
+	return value
+}
@@ -0,0 +1,67 @@
+package ggo
+
+import "testing"
+
+func Test_Equal(t *testing.T) {
+	multi := func(doc string) *Config {
+		c := NewConfig()
+		c.SetKeyMultiple("sync", true)
+		c.FromString(doc)
+		return c
+	}
+
+	a := multi("vlan 10 # edge\nsync 10.0.0.1\nsync 10.0.0.2\n# mtu 1500")
+	if !Equal(a, a.Clone()) || a.Hash() != a.Clone().Hash() {
+		t.Errorf("clone is not equal\n")
+	}
+
+	cases := []struct {
+		doc   string
+		opts  []EqualOption
+		equal bool
+	}{
+		{"vlan 10 # edge\nsync 10.0.0.1\nsync 10.0.0.2\n# mtu 1500", nil, true},
+		{"vlan 10 # edge\nsync 10.0.0.2\nsync 10.0.0.1\n# mtu 1500", nil, true},
+		{"vlan 10 # edge\nsync 10.0.0.2\nsync 10.0.0.1\n# mtu 1500", []EqualOption{LineOrder()}, false},
+		{"vlan 10\nsync 10.0.0.1\nsync 10.0.0.2\n# mtu 1500", nil, false},
+		{"## VLAN\nvlan 10\nsync 10.0.0.1\nsync 10.0.0.2\n# mtu 1500", []EqualOption{IgnoreComments()}, true},
+		{"vlan 10 # edge\nsync 10.0.0.1\nsync 10.0.0.2", nil, false},
+		{"vlan 10 # edge\nsync 10.0.0.1\nsync 10.0.0.2\n# mtu 9000", []EqualOption{IgnoreInactive()}, true},
+		{"vlan 010 # edge\nsync 10.0.0.1\nsync \"10.0.0.2\"\n# mtu 1500", nil, false},
+		{"vlan 010 # edge\nsync 10.0.0.1\nsync \"10.0.0.2\"\n# mtu 1500", []EqualOption{SemanticValues()}, true},
+		{"vlan 10 # edge\nsync 10.0.0.1\nsync 10.0.0.2\nmtu 1500", nil, false},
+	}
+	for i, tc := range cases {
+		b := multi(tc.doc)
+		if Equal(a, b, tc.opts...) != tc.equal {
+			t.Errorf("case %d: Equal is %v\n", i, !tc.equal)
+		}
+		if (a.Hash(tc.opts...) == b.Hash(tc.opts...)) != tc.equal {
+			t.Errorf("case %d: hashes disagree with Equal\n", i)
+		}
+	}
+
+	// A config equals its own written form.
+	d := multi("sync 10.0.0.2\nsync 10.0.0.1")
+	e := multi(d.String())
+	if !Equal(d, e) || d.Hash() != e.Hash() {
+		t.Errorf("config differs from its written form\n")
+	}
+
+	// Set entries have no position; they compare by value.
+	b, c := NewConfig(), NewConfig()
+	b.SetKeyMultiple("sync", true)
+	c.SetKeyMultiple("sync", true)
+	b.Set(ParseString("sync ::1"))
+	b.Replace(ParseString("sync ::2"))
+	c.Set(ParseString("sync ::2"))
+	c.Replace(ParseString("sync ::1"))
+	if !Equal(b, c) {
+		t.Errorf("configs built in different orders differ\n")
+	}
+	c.Replace(ParseString("sync 0::3"))
+	b.Replace(ParseString("sync ::3"))
+	if Equal(b, c) || !Equal(b, c, SemanticValues()) {
+		t.Errorf("semantic comparison of IPv6 addresses failed\n")
+	}
+}
@@ -1,21 +1,43 @@
 package ggo
 
 import (
-	"bufio"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 )
 
 type Config struct {
 	fields map[string]ConfigEntryInterface
 	multipleList map[string]bool
+	secretList map[string]bool
+	wrapWidth int
+	layout Layout
+	layoutDepth int
+	schema *Schema
+	strict bool
+	diagnostics []Diagnostic
+	version int
+	versionLine int
+	path string
+	journal *Journal
+	secretKey []byte
+	checksum bool
+	// source holds the content read by FromFile, for signature checks.
+	source []byte
+	// stamp identifies the file at path as read or last saved.
+	stamp *fileStamp
+	// raw holds the lines last loaded, for Reparse.
+	raw []string
 }
 
 func NewConfig() *Config {
 	c := new(Config)
 	c.multipleList = make(map[string]bool)
+	c.secretList = make(map[string]bool)
 	c.fields = make(map[string]ConfigEntryInterface)
 	return c
 }
@@ -26,23 +48,86 @@ func (f *Config) CopyScheme() *Config {
 	for k, v := range f.multipleList {
 		c.multipleList[k] = v
 	}
+	for k := range f.secretList {
+		c.secretList[k] = true
+	}
+	c.schema = f.schema
+	c.version = f.schema.Version()
+	c.strict = f.strict
 	c.fields = make(map[string]ConfigEntryInterface)
 
 	return c
 }
 
+// SetKeyMultiple sets whether name is multi-valued, overriding the schema
+// either way. Entries already stored under name are converted: a single
+// entry becomes the only value, and the values of a key no longer
+// multi-valued collapse into one entry the way duplicates do when parsing.
+// Use Reparse to recover values collapsed before a key was made
+// multi-valued.
 func (f *Config) SetKeyMultiple(name string, isMultple bool) {
-	if isMultple {
-		f.multipleList[name] = isMultple
-	} else if _, exists := f.multipleList[name]; exists {
-		delete(f.multipleList, name)
+	f.multipleList[name] = isMultple
+
+	before := f.snapshot(name)
+	f.convertKey(name)
+	f.record("convert", name, before)
+}
+
+// SetWrapWidth sets the line width String and Write wrap long entries at,
+// using backslash continuations. Zero disables wrapping.
+func (f *Config) SetWrapWidth(width int) {
+	f.wrapWidth = width
+}
+
+// SetSchema attaches a schema to the config. When a schema is present, a
+// commented-out line is only parsed as a disabled entry if its key is
+// declared in the schema; any other commented line is a comment.
+//
+// An empty config takes the schema's format version, so that configs
+// built from scratch are written as current. Stored entries are converted
+// to the multiplicity the schema declares, as by SetKeyMultiple.
+func (f *Config) SetSchema(s *Schema) {
+	f.schema = s
+	if len(f.fields) == 0 {
+		f.version = s.Version()
 	}
+	for _, k := range f.sortedKeys() {
+		before := f.snapshot(k)
+		f.convertKey(k)
+		f.record("convert", k, before)
+	}
+}
+
+func (f *Config) Schema() *Schema {
+	return f.schema
+}
+
+// canonicalName resolves deprecated key aliases declared in the schema.
+func (f *Config) canonicalName(name string) string {
+	name, _ = f.schema.Canonical(name)
+	return name
+}
+
+// Diagnostics returns what the last parse noticed but accepted, such as
+// deprecated key names.
+func (f *Config) Diagnostics() []Diagnostic {
+	return f.diagnostics
+}
+
+// SetStrictComments enables strict mode, where only lines marked with `#-`
+// are disabled entries and every other commented line is a comment.
+// Disabled entries are written with the `#-` marker in this mode.
+func (f *Config) SetStrictComments(strict bool) {
+	f.strict = strict
 }
 
 func (f *Config) isMultiple(name string) bool {
 	if isMultiple, exists := f.multipleList[name]; exists {
 		return isMultiple
 	}
+	if k := f.schema.Lookup(name); k != nil {
+		return k.Multiple
+	}
 	return false
 }
 
@@ -60,93 +145,226 @@ func (f *Config) setWhileParsing(e *ConfigEntry) {
 	}
 }
 
+// Set stores e under its key. For a multi-valued key e is added to the
+// values, replacing the entry holding the same value; for any other key it
+// replaces the stored entry. Use SetAll to replace every value of a
+// multi-valued key.
 func (f *Config) Set(e *ConfigEntry) {
 	if e == nil {
 		return
 	}
 
+	e = f.renamed(e)
 	name := e.Name()
+	before := f.snapshot(name)
+	f.store(e)
+	f.record("set", name, before)
+}
+
+// Add adds e to the values of a multi-valued key, replacing the entry
+// holding the same value. It returns ErrNotMultiple for any other key.
+func (f *Config) Add(e *ConfigEntry) error {
+	if e == nil {
+		return nil
+	}
 
-	if _, exists := f.fields[name]; exists {
+	e = f.renamed(e)
+	name := e.Name()
+	if !f.isMultiple(name) {
+		return fmt.Errorf("%s: %w", name, ErrNotMultiple)
+	}
+	before := f.snapshot(name)
+	f.store(e)
+	f.record("add", name, before)
+	return nil
+}
+
+// SetAll replaces every value of the key name with entries, whose own key
+// names are ignored. Without entries the key is deleted. A key that is not
+// multi-valued takes at most one entry; more return ErrNotMultiple.
+func (f *Config) SetAll(name string, entries ...*ConfigEntry) error {
+	name = f.canonicalName(name)
+	multiple := f.isMultiple(name)
+	if !multiple && len(entries) > 1 {
+		return fmt.Errorf("%s: %w", name, ErrNotMultiple)
+	}
+
+	before := f.snapshot(name)
+	delete(f.fields, name)
+	for _, e := range entries {
+		if e == nil {
+			continue
+		}
+		if e.Name() != name {
+			e = e.Copy().(*ConfigEntry)
+			e.name = name
+		}
+		f.store(e)
+	}
+	f.record("set", name, before)
+	return nil
+}
+
+// Replace stores e among the values of a multi-valued key, replacing the
+// entry holding the same value. For any other key it is the same as Set.
+// It reports whether an entry was replaced.
+func (f *Config) Replace(e *ConfigEntry) bool {
+	if e == nil {
+		return false
+	}
+
+	e = f.renamed(e)
+	name := e.Name()
+	_, exists := f.fields[name]
+	m, ok := f.fields[name].(*ConfigMultiEntry)
+	if !ok || !f.isMultiple(name) {
+		f.Set(e)
+		return exists
+	}
+
+	before := f.snapshot(name)
+	replaced := m.Replace(e)
+	f.record("replace", name, before)
+	return replaced
+}
+
+// renamed returns e under the canonical name of its key.
+func (f *Config) renamed(e *ConfigEntry) *ConfigEntry {
+	name := f.canonicalName(e.Name())
+	if name != e.Name() {
+		e = e.Copy().(*ConfigEntry)
+		e.name = name
+	}
+	return e
+}
+
+// store puts e in place the way the key's multiplicity requires: values
+// of multi-valued keys are always kept in a *ConfigMultiEntry, and any
+// other key holds a single *ConfigEntry.
+func (f *Config) store(e *ConfigEntry) {
+	name := e.Name()
+	if !f.isMultiple(name) {
 		f.fields[name] = e
-	} else {
-		if f.isMultiple(name) {
-			f.fields[name] = e.MakeMultiple()
-		} else {
-			f.fields[name] = e
+		return
+	}
+
+	switch v := f.fields[name].(type) {
+	case *ConfigMultiEntry:
+		if v.Entries == nil {
+			v.Entries = make(map[string]*ConfigEntry)
 		}
+		v.Replace(e)
+	case *ConfigEntry:
+		m := v.MakeMultiple()
+		m.Replace(e)
+		f.fields[name] = m
+	default:
+		f.fields[name] = e.MakeMultiple()
 	}
 }
 
 func (f *Config) Get(name string) ConfigEntryInterface {
-	return f.fields[name]
+	return f.fields[f.canonicalName(name)]
 }
 
 func (f *Config) Delete(name string) ConfigEntryInterface {
+	name = f.canonicalName(name)
 	r, exists := f.fields[name]
 
 	if exists {
+		before := f.snapshot(name)
 		delete(f.fields, name)
+		f.record("delete", name, before)
 		return r
 	} else {
 		return nil
 	}
 }
 
+// DeleteValue deletes the entry of name holding value and returns it, or
+// nil if there is none. A single-valued key is only deleted if it holds
+// value.
 func (f *Config) DeleteValue(name string, value string) *ConfigEntry {
+	name = f.canonicalName(name)
 	e, exists := f.fields[name]
 	if !exists {
 		return nil
 	}
 
+	before := f.snapshot(name)
+	var res *ConfigEntry
 	switch v := e.(type) {
 	case *ConfigEntry:
-		delete(f.fields, name)
-		return v
+		if v.Value == value {
+			delete(f.fields, name)
+			res = v
+		}
 	case *ConfigMultiEntry:
-		return v.Delete(value)
+		res = v.Delete(value)
 	}
-
-	return nil
+	if res != nil {
+		f.record("delete-value", name, before)
+	}
+	return res
 }
 
 func (f *Config) FromFile(file *os.File) error {
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		e := ParseString(line)
-		if e == nil {
-			continue
-		}
-		f.setWhileParsing(e)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	f.path = file.Name()
+	f.source = data
+	f.stamp = nil
+	if info, err := file.Stat(); err == nil {
+		f.stamp = newStamp(info, data)
 	}
+	return f.parseData(data)
+}
 
-	if err := scanner.Err(); err != nil {
-		return err
+// parseData reads the config from the content of a file. Lines are split
+// as FromString does, less the carriage returns of CRLF line ends.
+func (f *Config) parseData(data []byte) error {
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
 	}
-	return nil
+	if len(data) == 0 {
+		lines = nil
+	}
+	return f.parseLines(lines)
 }
 
-func (f *Config) FromString(str string) {
+// FromString reads the config from str. It returns ErrChecksum if the
+// document does not match its checksum trailer, or else the error
+// upgrading it, if any; see Upgrade.
+func (f *Config) FromString(str string) error {
 	f.fields = make(map[string]ConfigEntryInterface)
-	for _, v := range strings.Split(str,"\n") {
-		e := ParseString(v)
-		if e == nil {
-			continue
-		}
-		f.setWhileParsing(e)
-	}
+	f.source = nil
+	f.stamp = nil
+	return f.parseLines(strings.Split(str, "\n"))
 }
 
-func (f *Config) FromStrings(strs []string) {
+// FromStrings reads the config from lines, like FromString.
+func (f *Config) FromStrings(strs []string) error {
 	f.fields = make(map[string]ConfigEntryInterface, len(strs))
-	for _, v := range strs {
-		e := ParseString(v)
-		if e == nil {
-			continue
-		}
-		f.setWhileParsing(e)
+	f.source = nil
+	f.stamp = nil
+	return f.parseLines(strs)
+}
+
+// parseLines reads the config from the lines of a document.
+func (f *Config) parseLines(lines []string) error {
+	p := newParser(f)
+	for _, line := range lines {
+		p.feed(line)
 	}
+	p.finish()
+
+	if p.badChecksum {
+		return ErrChecksum
+	}
+	return f.Upgrade()
 }
 
 func (f *Config) ParseConfig(data interface{}) error {
@@ -154,11 +372,14 @@ func (f *Config) ParseConfig(data interface{}) error {
 
 	switch v := data.(type) {
 	case []byte:
-		f.FromString(string(v))
+		f.fields = make(map[string]ConfigEntryInterface)
+		f.source = nil
+		f.stamp = nil
+		err = f.parseData(v)
 	case string:
-		f.FromString(v)
+		err = f.FromString(v)
 	case []string:
-		f.FromStrings(v)
+		err = f.FromStrings(v)
 	case *os.File:
 		err = f.FromFile(v)
 	default:
@@ -174,10 +395,20 @@ func (f *Config) mergeSchemes(configs ...*Config) {
 			continue
 		}
 		for k, m := range c.multipleList {
-			if m {
-				f.SetKeyMultiple(k, true)
+			if _, set := f.multipleList[k]; m || !set {
+				f.SetKeyMultiple(k, m)
 			}
 		}
+		for k := range c.secretList {
+			f.SetKeySecret(k, true)
+		}
+		if c.schema != nil {
+			f.schema = c.schema
+		}
+		f.strict = f.strict || c.strict
+		if c.version > f.version {
+			f.version = c.version
+		}
 	}
 }
 
@@ -187,25 +418,39 @@ func (f *Config) mergeSingleKeys(configs ...*Config) {
 			continue
 		}
 		for k := range conf.fields {
-			if f.isMultiple(k) {
+			name := f.canonicalName(k)
+			if f.isMultiple(name) {
+				continue
+			}
+			e := collapse(conf.fields[k].Copy())
+			if e == nil {
 				continue
 			}
-			f.Set(conf.Get(k).(*ConfigEntry))
+			prev, _ := f.fields[name].(*ConfigEntry)
+			f.Set(inheritDoc(e, prev))
 		}
 	}
 }
 
 func (f *Config) mergeMultiKeys(configs ...*Config) {
-	for k := range f.multipleList {
-		v := new(ConfigMultiEntry)
-		for _, c := range configs {
-			if c == nil {
+	for _, c := range configs {
+		if c == nil {
+			continue
+		}
+		for k, e := range c.fields {
+			k = f.canonicalName(k)
+			if !f.isMultiple(k) {
 				continue
 			}
-			v.Merge(c.fields[k])
-		}
-		if v.Entries != nil {
-			f.fields[k] = v
+			v, exists := f.fields[k].(*ConfigMultiEntry)
+			if !exists {
+				v = new(ConfigMultiEntry)
+				v.name = k
+			}
+			v.Merge(e)
+			if v.Entries != nil {
+				f.fields[k] = v
+			}
 		}
 	}
 }
@@ -260,6 +505,32 @@ func MergeSchemes(configs ...*Config) *Config {
 	return f
 }
 
+func (f *Config) sortedKeys() []string {
+	keys := make([]string, 0, len(f.fields))
+	for k := range f.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lines renders the config as physical lines in the configured layout,
+// wrapped at the configured width.
+func (f *Config) lines() []string {
+	var res []string
+	if f.version > 0 {
+		res = append(res, versionHeader+" "+strconv.Itoa(f.version))
+	}
+	if f.layout != LayoutFlat {
+		return append(res, f.groupedLines()...)
+	}
+
+	for _, k := range f.sortedKeys() {
+		res = append(res, f.entryLines(f.fields[k], k, "")...)
+	}
+	return res
+}
+
 func (f *Config) Write(Filename string) error {
 	file, err := os.Create(Filename)
 	if err != nil {
@@ -267,29 +538,20 @@ func (f *Config) Write(Filename string) error {
 	}
 	defer file.Close()
 
-	keys := make([]string, 0, len(f.fields))
-	for k, _ := range f.fields {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
+	_, err = f.WriteTo(file)
+	return err
+}
 
-	w := bufio.NewWriter(file)
-	for _, k := range keys {
-		v := f.fields[k]
-		w.WriteString(v.StringLn())
-	}
-	return w.Flush()
+// WriteTo writes the config to w as Write writes it to a file.
+func (f *Config) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(f.fileContent())
+	return int64(n), err
 }
 
+// String renders the config as Write does, with the values of secret
+// keys redacted.
 func (f *Config) String() string {
-	var res string
-	for _, v := range f.fields {
-		res += v.StringLn()
-	}
-	if len(res) > 1 {
-		res = res[:len(res) - 1]
-	}
-	return res
+	return strings.Join(f.redacted().lines(), "\n")
 }
 
 func (f *Config) Len() int {
@@ -273,6 +273,18 @@ func Test_X_GgoConfig_Parse(t *testing.T) {
 	file.SetKeyMultiple("sync-neighbour", true)
 	file.FromStrings(testData)
 
+	docs := map[string]string{
+		"tb.asym.syn_ack.low.32.speed":     "SYN+ACK",
+		"tb.asym.syn_ack.low.24.speed":     "",
+		"tb.sym.tcp.syn.synced.32.setting": "SYN_IN_OUT",
+		"tb.sym.dns.24.speed":              "/24",
+	}
+	for name, doc := range docs {
+		if e, ok := file.Get(name).(*ConfigEntry); !ok || e.DocComment != doc {
+			t.Errorf("'%s' invalid doc comment %v\n", name, file.Get(name))
+		}
+	}
+
 	file.checkEntry(t, true, "sym.prot.ipv4", "198.18.1.2/24", "")
 	file.checkEntry(t, true, "sym.prot.vlan", "106", "")
 	file.checkEntry(t, true, "sym.raw.ipv4", "198.18.0.2/24", "")
@@ -305,8 +317,6 @@ func Test_X_GgoConfig_Parse(t *testing.T) {
 	file.checkEntry(t, false, "tb.sym.ipv4_fragmented.bps.24.speed", "6250000", "")
 	file.checkEntry(t, false, "tb.asym.ipv4_fragmented.32.speed", "1600", "")
 	file.checkEntry(t, false, "tb.asym.ipv4_fragmented.bps.24.speed", "625000", "")
-	file.checkEntry(t, false, "TCP", "", "")
-	file.checkEntry(t, false, "SYN", "", "")
 	file.checkEntry(t, false, "tb.asym.syn.cookie_per_src.32.speed", "50", "")
 	file.checkEntry(t, false, "tb.asym.syn.cookie_per_src.32.setting", "0", "")
 	file.checkEntry(t, false, "tb.sym.syn.ttl.32.speed", "1536", "")
@@ -321,12 +331,10 @@ func Test_X_GgoConfig_Parse(t *testing.T) {
 	file.checkEntry(t, false, "tb.asym.syn.options.24.speed", "5120", "")
 	file.checkEntry(t, false, "tb.asym.syn.retransmit.32.speed", "1600", "")
 	file.checkEntry(t, false, "tb.asym.syn.retransmit.24.speed", "30720", "")
-	file.checkEntry(t, false, "SYN+ACK", "", "")
 	file.checkEntry(t, false, "tb.asym.syn_ack.low.32.speed", "300", "")
 	file.checkEntry(t, false, "tb.asym.syn_ack.low.24.speed", "5120", "")
 	file.checkEntry(t, false, "tb.asym.syn_ack.final.32.speed", "200", "")
 	file.checkEntry(t, false, "tb.asym.syn_ack.final.24.speed", "192000", "")
-	file.checkEntry(t, false, "SYN_IN_OUT", "", "")
 	file.checkEntry(t, false, "tb.sym.tcp.syn.synced.32.setting", "1000", "")
 	file.checkEntry(t, false, "tb.sym.tcp.syn.not_synced.32.speed", "1280", "")
 	file.checkEntry(t, false, "tb.sym.tcp.syn.not_synced.32.setting", "500", "")
@@ -347,7 +355,6 @@ func Test_X_GgoConfig_Parse(t *testing.T) {
 	file.checkEntry(t, false, "tb.asym.tcp.unknown.grace.32.speed", "0", "")
 	file.checkEntry(t, false, "tb.asym.tcp.unknown.grace.32.setting", "0", "")
 	file.checkEntry(t, false, "tb.asym.tcp.closing.32.speed", "2560", "")
-	file.checkEntry(t, false, "asymmetric", "buckets", "")
 	file.checkEntry(t, false, "tb.sym.memcached_amplifications.32.speed", "0", "")
 	file.checkEntry(t, false, "tb.asym.memcached_amplifications.32.speed", "0", "")
 	file.checkEntry(t, false, "tb.asym.dns.32.speed", "7000", "")
@@ -357,13 +364,11 @@ func Test_X_GgoConfig_Parse(t *testing.T) {
 	file.checkEntry(t, false, "tb.asym.dns.not_any.32.speed", "500", "")
 	file.checkEntry(t, false, "tb.asym.sip.invite.32.speed", "1280", "")
 	file.checkEntry(t, false, "tb.asym.sip.register.32.speed", "1280", "")
-	file.checkEntry(t, false, "symmetric", "buckets", "")
 	file.checkEntry(t, false, "tb.sym.udp.32.speed", "800", "")
 	file.checkEntry(t, false, "tb.sym.dns.32.speed", "5000", "")
 	file.checkEntry(t, false, "tb.sym.sip.invite.32.speed", "1600", "")
 	file.checkEntry(t, false, "tb.sym.sip.register.32.speed", "1600", "")
 	file.checkEntry(t, false, "tb.sym.ipv4_others.32.speed", "384", "")
-	file.checkEntry(t, false, "/24", "", "")
 	file.checkEntry(t, false, "tb.sym.dns.24.speed", "0", "")
 	file.checkEntry(t, false, "tb.sym.dns.24.setting", "1000", "")
 	file.checkEntry(t, false, "tb.sym.ipv4_others.24.speed", "0", "")
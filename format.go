@@ -0,0 +1,215 @@
+package ggo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FormatOptions control FormatWith.
+type FormatOptions struct {
+	// Scheme decides, through its schema and comment mode, which commented
+	// lines are disabled entries. It may be nil.
+	Scheme *Config
+	// Sort orders entries by key within each group. Comment lines directly
+	// above an entry move with it.
+	Sort bool
+}
+
+// fmtLine is a physical line of a document being formatted. Lines that
+// are not entries are kept as text.
+type fmtLine struct {
+	depth int
+	text  string
+	entry *ConfigEntry
+	// prefix is the written key: the disabled marker and the key name.
+	prefix string
+	// verbatim lines belong to a continued line and are kept as written.
+	verbatim bool
+}
+
+func (l *fmtLine) render(width int) string {
+	if l.verbatim {
+		return l.text
+	}
+	indent := strings.Repeat("\t", l.depth)
+	if l.entry == nil {
+		if l.text == "" {
+			return ""
+		}
+		return indent + l.text
+	}
+
+	res := indent + l.prefix
+	if l.entry.Value != "" {
+		res += strings.Repeat(" ", width-len(l.prefix)+1) + l.entry.Value
+	}
+	if l.entry.Comment != "" {
+		res += " # " + l.entry.Comment
+	}
+	return res
+}
+
+// Format rewrites a document in canonical form: one space between tokens,
+// values aligned into a column within each blank-line-separated group,
+// `# ` before disabled entries and block contents indented with tabs.
+// Comments and continued lines are kept as written, less trailing
+// whitespace. A checksum trailer is recomputed for the formatted content.
+// Formatting is idempotent.
+func Format(doc []byte) ([]byte, error) {
+	return FormatWith(doc, FormatOptions{})
+}
+
+func FormatWith(doc []byte, opts FormatOptions) ([]byte, error) {
+	scheme := opts.Scheme
+	if scheme == nil {
+		scheme = NewConfig()
+	}
+
+	text := strings.ReplaceAll(string(doc), "\r\n", "\n")
+	raw := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	var groups [][]*fmtLine
+	var group []*fmtLine
+	flush := func() {
+		if len(group) > 0 {
+			groups = append(groups, group)
+			group = nil
+		}
+	}
+
+	section := ""
+	blocks := make([]string, 0)
+	qualify := func(name string) string {
+		parts := make([]string, 0, len(blocks)+2)
+		if section != "" {
+			parts = append(parts, section)
+		}
+		parts = append(parts, blocks...)
+		return strings.Join(append(parts, name), ".")
+	}
+
+	continued := false
+	for i, line := range raw {
+		trimmed := strings.TrimSpace(line)
+		l := &fmtLine{depth: len(blocks), text: trimmed}
+
+		switch {
+		case continued || isContinued(trimmed):
+			l.verbatim = true
+			l.text = strings.TrimRight(line, " \t")
+		case trimmed == "":
+			flush()
+			continue
+		case len(trimmed) > 1 && trimmed[0] == '[' && trimmed[len(trimmed)-1] == ']':
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			l.text = "[" + section + "]"
+		case trimmed == "}":
+			if len(blocks) == 0 {
+				return nil, fmt.Errorf("line %d: unexpected '}'", i+1)
+			}
+			blocks = blocks[:len(blocks)-1]
+			l.depth = len(blocks)
+		default:
+			if name, rest, ok := blockOpening(trimmed); ok {
+				rest = strings.TrimSpace(rest)
+				if strings.HasSuffix(rest, "}") {
+					inner := strings.TrimSpace(rest[:len(rest)-1])
+					l.text = name + " { " + inner + " }"
+					if inner == "" {
+						l.text = name + " {}"
+					}
+				} else if rest == "" {
+					blocks = append(blocks, name)
+					l.text = name + " {"
+				} else {
+					return nil, fmt.Errorf("line %d: block content must start on its own line", i+1)
+				}
+				break
+			}
+			written := ""
+			e := scheme.parseLine(trimmed, func(name string) string {
+				written = name
+				return qualify(name)
+			})
+			if e != nil {
+				l.entry = e
+				l.prefix = written
+				if !e.IsActive && (scheme.strict || isDisabledMarked(trimmed)) {
+					l.prefix = disabledMarker + written
+				} else if !e.IsActive {
+					l.prefix = "# " + written
+				}
+			}
+		}
+
+		continued = isContinued(strings.TrimRight(line, " \t"))
+		group = append(group, l)
+	}
+	flush()
+
+	if len(blocks) > 0 {
+		return nil, errors.New("unterminated block '" + blocks[len(blocks)-1] + "'")
+	}
+
+	var out strings.Builder
+	for i, g := range groups {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		if opts.Sort {
+			g = sortGroup(g)
+		}
+		width := 0
+		for _, l := range g {
+			if l.entry != nil && l.entry.Value != "" && len(l.prefix) > width {
+				width = len(l.prefix)
+			}
+		}
+		for _, l := range g {
+			out.WriteString(l.render(width))
+			out.WriteString("\n")
+		}
+	}
+	return reseal([]byte(out.String())), nil
+}
+
+// sortGroup sorts the entries of a group by key. Comment lines stay above
+// the entry following them; any other line is a barrier entries are not
+// moved across.
+func sortGroup(g []*fmtLine) []*fmtLine {
+	type unit struct {
+		key   string
+		lines []*fmtLine
+	}
+
+	res := make([]*fmtLine, 0, len(g))
+	var units []unit
+	var pending []*fmtLine
+	flush := func() {
+		sort.SliceStable(units, func(i, j int) bool {
+			return units[i].key < units[j].key
+		})
+		for _, u := range units {
+			res = append(res, u.lines...)
+		}
+		res = append(res, pending...)
+		units, pending = nil, nil
+	}
+
+	for _, l := range g {
+		switch {
+		case l.entry != nil:
+			units = append(units, unit{key: l.entry.Name(), lines: append(pending, l)})
+			pending = nil
+		case !l.verbatim && strings.HasPrefix(l.text, "#"):
+			pending = append(pending, l)
+		default:
+			flush()
+			res = append(res, l)
+		}
+	}
+	flush()
+	return res
+}
@@ -0,0 +1,78 @@
+package ggo
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	doc := "sync\t \t  239.0.0.3\n" +
+		"sync              239.1.0.3   \n" +
+		"#sflow.drop.pool\t\t0\n" +
+		"sflow.drop.rate\t\t0 #1000\n" +
+		"\n" +
+		"\n" +
+		"## Buckets\n" +
+		"tb.sym.syn {\n" +
+		"  #ttl.32.speed 1536\n" +
+		"      low.32.speed   640\n" +
+		"}\n" +
+		"acl.list 10.0.0.0/8,\\\n" +
+		"    192.168.0.0/16\n" +
+		"eth-0_1\n"
+
+	expected := "sync              239.0.0.3\n" +
+		"sync              239.1.0.3\n" +
+		"# sflow.drop.pool 0\n" +
+		"sflow.drop.rate   0 # 1000\n" +
+		"\n" +
+		"## Buckets\n" +
+		"tb.sym.syn {\n" +
+		"\t# ttl.32.speed 1536\n" +
+		"\tlow.32.speed   640\n" +
+		"}\n" +
+		"acl.list 10.0.0.0/8,\\\n" +
+		"    192.168.0.0/16\n" +
+		"eth-0_1\n"
+
+	got, err := Format([]byte(doc))
+	if err != nil || string(got) != expected {
+		t.Errorf("unexpected format (%v):\n%s\n", err, got)
+	}
+
+	again, err := Format(got)
+	if err != nil || string(again) != string(got) {
+		t.Errorf("format is not idempotent:\n%s\n", again)
+	}
+}
+
+func TestFormat_Sort(t *testing.T) {
+	doc := "b 1\n" +
+		"# doc for a\n" +
+		"#-a 2\n" +
+		"c 3\n" +
+		"\n" +
+		"z 1\n" +
+		"y 2\n"
+
+	expected := "#-a 2\n" +
+		"b   1\n" +
+		"c   3\n" +
+		"\n" +
+		"y 2\n" +
+		"z 1\n"
+
+	scheme := NewConfig()
+	scheme.SetStrictComments(true)
+	got, err := FormatWith([]byte(doc), FormatOptions{Scheme: scheme, Sort: true})
+	expected = "# doc for a\n" + expected
+	if err != nil || string(got) != expected {
+		t.Errorf("unexpected format (%v):\n%s\n", err, got)
+	}
+}
+
+func TestFormat_Errors(t *testing.T) {
+	if _, err := Format([]byte("a 1\n}\n")); err == nil {
+		t.Error("unbalanced block accepted")
+	}
+	if _, err := Format([]byte("a {\nb 1\n")); err == nil {
+		t.Error("unterminated block accepted")
+	}
+}
@@ -0,0 +1,155 @@
+package ggo
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// checksumTrailer starts the line closing a document with the SHA-256 of
+// the lines above it, each ending with a newline, such as
+// `#ggo:sha256 9f86d0...`.
+const checksumTrailer = "#ggo:sha256"
+
+// ErrChecksum is returned by every loader, such as FromFile and
+// FromString, for documents whose content does not match their checksum
+// trailer.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// SetChecksum makes Write end the file with a checksum trailer. Loading a
+// document that has one turns it on.
+func (f *Config) SetChecksum(enabled bool) {
+	f.checksum = enabled
+}
+
+// fileLines renders the config as written to files: its lines followed,
+// if enabled, by the checksum trailer.
+func (f *Config) fileLines() []string {
+	lines := f.lines()
+	if !f.checksum {
+		return lines
+	}
+	sum := sha256.New()
+	for _, line := range lines {
+		sum.Write([]byte(line + "\n"))
+	}
+	return append(lines, checksumTrailer+" "+hex.EncodeToString(sum.Sum(nil)))
+}
+
+// reseal recomputes the checksum trailer of a rewritten document that had
+// one. The old trailer, and blank lines before it, are dropped and a new
+// trailer over the remaining lines closes the document. Documents without
+// a trailer are returned as they are.
+func reseal(doc []byte) []byte {
+	lines := strings.Split(strings.TrimSuffix(string(doc), "\n"), "\n")
+	res := make([]string, 0, len(lines))
+	found := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), checksumTrailer) {
+			found = true
+			continue
+		}
+		res = append(res, line)
+	}
+	if !found {
+		return doc
+	}
+	for len(res) > 0 && strings.TrimSpace(res[len(res)-1]) == "" {
+		res = res[:len(res)-1]
+	}
+
+	// Lines are hashed as FromFile reads them, without carriage returns.
+	sum := sha256.New()
+	var out strings.Builder
+	for _, line := range res {
+		sum.Write([]byte(strings.TrimSuffix(line, "\r") + "\n"))
+		out.WriteString(line + "\n")
+	}
+	out.WriteString(checksumTrailer + " " + hex.EncodeToString(sum.Sum(nil)) + "\n")
+	return []byte(out.String())
+}
+
+// checkTrailer handles a physical line starting with the checksum
+// trailer. Lines are hashed as they are fed, so the sum covers every line
+// above it.
+func (p *parser) checkTrailer(line string) {
+	p.conf.checksum = true
+	p.trailer = true
+	expected := strings.TrimSpace(line[len(checksumTrailer):])
+	if hex.EncodeToString(p.sum.Sum(nil)) != strings.ToLower(expected) {
+		p.badChecksum = true
+		p.report("", "content does not match its checksum")
+	}
+}
+
+// signaturePath returns where the detached signature of a config file is
+// stored.
+func signaturePath(path string) string {
+	return path + ".sig"
+}
+
+// SignFile writes the detached ed25519 signature of the file at path, in
+// base64, next to it.
+func SignFile(path string, key ed25519.PrivateKey) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+	return os.WriteFile(signaturePath(path), []byte(sig+"\n"), 0644)
+}
+
+// readSignature reads a signature file holding either the raw signature
+// or its base64 encoding.
+func readSignature(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == ed25519.SignatureSize {
+		return data, nil
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("%s: malformed signature", path)
+	}
+	return sig, nil
+}
+
+// verify checks the detached signature of the file the config was read
+// from against the content FromFile read, so that later changes to the
+// file are not taken into account.
+func (f *Config) verify(key ed25519.PublicKey) error {
+	if f.path == "" || f.source == nil {
+		return errors.New("config was not read from a file")
+	}
+	sig, err := readSignature(signaturePath(f.path))
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, f.source, sig) {
+		return fmt.Errorf("%s: bad signature", f.path)
+	}
+	return nil
+}
+
+// MergeVerified merges configs like Merge, after checking that every one
+// of them was read by FromFile from a file with a valid detached signature
+// `<file>.sig` made with key. If any layer fails the check, nothing is
+// merged and the error names it.
+func MergeVerified(key ed25519.PublicKey, configs ...*Config) (*Config, error) {
+	for _, c := range configs {
+		if c == nil {
+			continue
+		}
+		if err := c.verify(key); err != nil {
+			return nil, err
+		}
+	}
+	return Merge(configs...), nil
+}
@@ -0,0 +1,161 @@
+package ggo
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readConfigFile(t *testing.T, path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	c := NewConfig()
+	return c, c.FromFile(file)
+}
+
+func Test_Checksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ggo.conf")
+	c := NewConfig()
+	c.FromString("vlan 10\n# mtu 1500")
+	c.SetChecksum(true)
+	if err := c.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	sum := sha256.Sum256([]byte("# mtu 1500\nvlan 10\n"))
+	if len(lines) != 3 || lines[2] != "#ggo:sha256 "+hex.EncodeToString(sum[:]) {
+		t.Errorf("unexpected file %q\n", data)
+	}
+
+	loaded, err := readConfigFile(t, path)
+	if err != nil || len(loaded.Diagnostics()) != 0 {
+		t.Errorf("valid checksum rejected: %v %v\n", err, loaded.Diagnostics())
+	}
+	if loaded.Get("ggo:sha256") != nil {
+		t.Errorf("trailer read as an entry\n")
+	}
+	// The trailer is kept when the config is written back.
+	loaded.Write(path)
+	if written, _ := os.ReadFile(path); string(written) != string(data) {
+		t.Errorf("rewritten as %q\n", written)
+	}
+
+	os.WriteFile(path, []byte(strings.Replace(string(data), "vlan 10", "vlan 11", 1)), 0644)
+	if _, err := readConfigFile(t, path); err != ErrChecksum {
+		t.Errorf("tampered content accepted: %v\n", err)
+	}
+
+	os.WriteFile(path, append(data, "vlan 12\n"...), 0644)
+	if _, err := readConfigFile(t, path); err != ErrChecksum {
+		t.Errorf("content after the trailer accepted: %v\n", err)
+	}
+
+	tampered := strings.Replace(string(data), "vlan 10", "vlan 11", 1)
+	if err := c.FromString(tampered); err != ErrChecksum {
+		t.Errorf("FromString accepted tampered content: %v\n", err)
+	}
+	if d := c.Diagnostics(); len(d) != 1 || d[0].Line != 3 {
+		t.Errorf("mismatch not reported: %v\n", d)
+	}
+	if err := c.FromStrings(strings.Split(tampered, "\n")); err != ErrChecksum {
+		t.Errorf("FromStrings accepted tampered content: %v\n", err)
+	}
+	if err := c.ParseConfig(tampered); err != ErrChecksum {
+		t.Errorf("ParseConfig accepted tampered content: %v\n", err)
+	}
+}
+
+func Test_MergeVerified(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+
+	dir := t.TempDir()
+	base, site := filepath.Join(dir, "base.conf"), filepath.Join(dir, "site.conf")
+	os.WriteFile(base, []byte("vlan 10\nmtu 1500\n"), 0644)
+	os.WriteFile(site, []byte("vlan 20\n"), 0644)
+	for _, path := range []string{base, site} {
+		if err := SignFile(path, priv); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, _ := readConfigFile(t, base)
+	s, _ := readConfigFile(t, site)
+	merged, err := MergeVerified(pub, b, s)
+	if err != nil {
+		t.Fatalf("signed layers rejected: %v\n", err)
+	}
+	merged.checkEntry(t, true, "vlan", "20", "")
+
+	if _, err := MergeVerified(otherPub, b, s); err == nil {
+		t.Errorf("layers accepted with the wrong key\n")
+	}
+
+	// The signature covers the content read, not the file as changed later.
+	os.WriteFile(site, []byte("vlan 30\n"), 0644)
+	if _, err := MergeVerified(pub, b, s); err != nil {
+		t.Errorf("loaded layer rejected: %v\n", err)
+	}
+	s, _ = readConfigFile(t, site)
+	if _, err := MergeVerified(pub, b, s); err == nil || !strings.Contains(err.Error(), "site.conf") {
+		t.Errorf("tampered layer accepted: %v\n", err)
+	}
+
+	unsigned := NewConfig()
+	unsigned.FromString("vlan 40")
+	if _, err := MergeVerified(pub, b, unsigned); err == nil {
+		t.Errorf("layer not read from a file accepted\n")
+	}
+}
+
+func Test_ChecksumRewrite(t *testing.T) {
+	c := NewConfig()
+	c.FromString("vlan 10\nsflow.rate 4000")
+	c.SetChecksum(true)
+	doc := c.fileContent()
+
+	rules := []MigrationRule{{From: "sflow.rate", To: "sflow.sampling"}}
+	migrated, err := MigrateDocument(doc, nil, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted, err := Format(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(formatted) == string(doc) {
+		t.Errorf("document not reformatted\n")
+	}
+
+	for name, data := range map[string][]byte{"migrated": migrated, "formatted": formatted} {
+		path := filepath.Join(t.TempDir(), "ggo.conf")
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := readConfigFile(t, path)
+		if err != nil {
+			t.Errorf("%s document: %v\n%s\n", name, err, data)
+			continue
+		}
+		if strings.Count(string(data), checksumTrailer) != 1 {
+			t.Errorf("%s document has %d trailers\n", name, strings.Count(string(data), checksumTrailer))
+		}
+		if name == "migrated" {
+			loaded.checkEntry(t, true, "sflow.sampling", "4000", "")
+		}
+	}
+
+	again, _ := Format(formatted)
+	if string(again) != string(formatted) {
+		t.Errorf("formatting a checksummed document is not idempotent\n")
+	}
+}
@@ -0,0 +1,140 @@
+package ggo
+
+import (
+	"iter"
+	"sort"
+	"strings"
+)
+
+// Keys returns the stored keys in document order: keys read from a
+// document by the line of their first entry, followed by keys set
+// otherwise in sorted order.
+func (f *Config) Keys() []string {
+	keys := f.SortedKeys()
+	first := make(map[string]int, len(keys))
+	for _, k := range keys {
+		first[k] = firstLine(f.fields[k])
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := first[keys[i]], first[keys[j]]
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		return a < b
+	})
+	return keys
+}
+
+// firstLine returns the lowest source line of the entries of e, 0 if none
+// was read from a document.
+func firstLine(e ConfigEntryInterface) int {
+	switch v := e.(type) {
+	case *ConfigEntry:
+		return v.line
+	case *ConfigMultiEntry:
+		res := 0
+		for _, entry := range v.Entries {
+			if entry.line > 0 && (res == 0 || entry.line < res) {
+				res = entry.line
+			}
+		}
+		return res
+	}
+	return 0
+}
+
+// SortedKeys returns the stored keys in sorted order.
+func (f *Config) SortedKeys() []string {
+	return f.sortedKeys()
+}
+
+// Range calls fn for every key in sorted order until fn returns false.
+// Keys may be deleted or set while ranging; keys set are not visited.
+func (f *Config) Range(fn func(name string, e ConfigEntryInterface) bool) {
+	for _, k := range f.sortedKeys() {
+		e, exists := f.fields[k]
+		if exists && !fn(k, e) {
+			return
+		}
+	}
+}
+
+// All iterates over the keys in sorted order, like Range.
+func (f *Config) All() iter.Seq2[string, ConfigEntryInterface] {
+	return f.Range
+}
+
+// Active iterates over every active entry in key and value order.
+func (f *Config) Active() iter.Seq2[string, *ConfigEntry] {
+	return func(yield func(string, *ConfigEntry) bool) {
+		for name, e := range f.All() {
+			for _, entry := range entriesOf(e) {
+				if entry.IsActive && !yield(name, entry) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// WithPrefix iterates in sorted order over prefix itself and the keys
+// under it, such as `sflow.rate` for the prefix `sflow`.
+func (f *Config) WithPrefix(prefix string) iter.Seq2[string, ConfigEntryInterface] {
+	prefix = strings.TrimSuffix(prefix, ".")
+	return func(yield func(string, ConfigEntryInterface) bool) {
+		for name, e := range f.All() {
+			if name != prefix && !strings.HasPrefix(name, prefix+".") {
+				continue
+			}
+			if !yield(name, e) {
+				return
+			}
+		}
+	}
+}
+
+// entriesOf returns the entries of a stored key in value order.
+func entriesOf(e ConfigEntryInterface) []*ConfigEntry {
+	switch v := e.(type) {
+	case *ConfigEntry:
+		return []*ConfigEntry{v}
+	case *ConfigMultiEntry:
+		res := make([]*ConfigEntry, 0, len(v.Entries))
+		for _, value := range v.sortedValues() {
+			res = append(res, v.Entries[value])
+		}
+		return res
+	}
+	return nil
+}
+
+// Values returns the values in sorted order.
+func (e *ConfigMultiEntry) Values() []string {
+	return e.sortedValues()
+}
+
+// Range calls fn for every value in sorted order until fn returns false.
+func (e *ConfigMultiEntry) Range(fn func(value string, entry *ConfigEntry) bool) {
+	for _, value := range e.sortedValues() {
+		entry, exists := e.Entries[value]
+		if exists && !fn(value, entry) {
+			return
+		}
+	}
+}
+
+// All iterates over the values in sorted order, like Range.
+func (e *ConfigMultiEntry) All() iter.Seq2[string, *ConfigEntry] {
+	return e.Range
+}
+
+// Active iterates over the active values in sorted order.
+func (e *ConfigMultiEntry) Active() iter.Seq2[string, *ConfigEntry] {
+	return func(yield func(string, *ConfigEntry) bool) {
+		for value, entry := range e.All() {
+			if entry.IsActive && !yield(value, entry) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package ggo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Iteration(t *testing.T) {
+	c := NewConfig()
+	c.SetKeyMultiple("sync.neighbour", true)
+	c.FromString("vlan 10\nsync.neighbour 10.0.0.2\n# sync.neighbour 10.0.0.1\nsflow.rate 1k\nsync.neighbour 10.0.0.3")
+	c.Set(ParseString("mtu 9000"))
+	c.Set(ParseString("# a 1"))
+
+	if keys := c.Keys(); !reflect.DeepEqual(keys, []string{"vlan", "sync.neighbour", "sflow.rate", "a", "mtu"}) {
+		t.Errorf("Keys = %v\n", keys)
+	}
+	if keys := c.SortedKeys(); !reflect.DeepEqual(keys, []string{"a", "mtu", "sflow.rate", "sync.neighbour", "vlan"}) {
+		t.Errorf("SortedKeys = %v\n", keys)
+	}
+
+	var visited []string
+	c.Range(func(name string, e ConfigEntryInterface) bool {
+		visited = append(visited, name)
+		c.Delete("vlan")
+		return name != "sflow.rate"
+	})
+	if !reflect.DeepEqual(visited, []string{"a", "mtu", "sflow.rate"}) {
+		t.Errorf("Range visited %v\n", visited)
+	}
+
+	var active []string
+	for name, e := range c.Active() {
+		active = append(active, name+" "+e.Value)
+	}
+	if !reflect.DeepEqual(active, []string{"mtu 9000", "sflow.rate 1k", "sync.neighbour 10.0.0.2", "sync.neighbour 10.0.0.3"}) {
+		t.Errorf("Active = %v\n", active)
+	}
+
+	var prefixed []string
+	for name := range c.WithPrefix("sync") {
+		prefixed = append(prefixed, name)
+	}
+	for name := range c.WithPrefix("s") {
+		prefixed = append(prefixed, name)
+	}
+	if !reflect.DeepEqual(prefixed, []string{"sync.neighbour"}) {
+		t.Errorf("WithPrefix = %v\n", prefixed)
+	}
+
+	m := c.Get("sync.neighbour").(*ConfigMultiEntry)
+	if values := m.Values(); !reflect.DeepEqual(values, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}) {
+		t.Errorf("Values = %v\n", values)
+	}
+	var values []string
+	for value := range m.Active() {
+		values = append(values, value)
+		break
+	}
+	if !reflect.DeepEqual(values, []string{"10.0.0.2"}) {
+		t.Errorf("Active values = %v\n", values)
+	}
+}
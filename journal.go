@@ -0,0 +1,215 @@
+package ggo
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Change is an edit recorded by a Journal. Old and New hold the key as
+// written before and after the edit, one line per value, and are empty
+// where the key did not exist.
+type Change struct {
+	Time  time.Time `json:"time"`
+	Actor string    `json:"actor,omitempty"`
+	Op    string    `json:"op"`
+	Key   string    `json:"key"`
+	Old   string    `json:"old,omitempty"`
+	New   string    `json:"new,omitempty"`
+	// Batch is shared by the changes of one Update, which are undone and
+	// redone together. It is 0 for changes made on their own.
+	Batch int `json:"batch,omitempty"`
+
+	before ConfigEntryInterface
+	after  ConfigEntryInterface
+}
+
+// Journal records the edits made through Set, Add, SetAll, Delete,
+// DeleteValue, Replace, Normalize and Update, and lets them be undone and
+// redone. Entries changed directly, without going through the Config, are
+// not recorded.
+type Journal struct {
+	// Actor is recorded with every change.
+	Actor string
+
+	changes []Change
+	// undone counts the changes at the end of changes that were undone.
+	undone int
+	// batches counts the batches started; batch is the current one, if any.
+	batches int
+	batch   int
+
+	log    io.Writer
+	closer io.Closer
+	// err is the first error writing the log.
+	err error
+}
+
+// EnableJournal attaches a new journal to the config and returns it.
+func (f *Config) EnableJournal() *Journal {
+	f.journal = new(Journal)
+	return f.journal
+}
+
+func (f *Config) Journal() *Journal {
+	return f.journal
+}
+
+// Changes returns the recorded changes that are in effect, oldest first.
+func (j *Journal) Changes() []Change {
+	return j.changes[:len(j.changes)-j.undone]
+}
+
+// LogTo appends every change from now on, including undo and redo, to w
+// as a line of JSON.
+func (j *Journal) LogTo(w io.Writer) {
+	j.log = w
+}
+
+// OpenLog appends the audit log to the file at path, usually next to the
+// config file, creating it if needed.
+func (j *Journal) OpenLog(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	j.Close()
+	j.log, j.closer = file, file
+	return nil
+}
+
+// Err returns the first error writing the audit log. Changes are still
+// recorded, and can be undone, after the log failed.
+func (j *Journal) Err() error {
+	return j.err
+}
+
+// Close closes the log file opened by OpenLog. It returns the first error
+// writing the log, if any, or else the error closing the file.
+func (j *Journal) Close() error {
+	var err error
+	if j.closer != nil {
+		err = j.closer.Close()
+	}
+	j.log, j.closer = nil, nil
+	if j.err != nil {
+		return j.err
+	}
+	return err
+}
+
+func (j *Journal) write(c Change) {
+	if j.log == nil {
+		return
+	}
+	data, err := json.Marshal(c)
+	if err == nil {
+		_, err = j.log.Write(append(data, '\n'))
+	}
+	if err != nil && j.err == nil {
+		j.err = err
+	}
+}
+
+// record journals the edit of key, given a copy of the key before it.
+func (f *Config) record(op string, key string, before ConfigEntryInterface) {
+	j := f.journal
+	if j == nil {
+		return
+	}
+	c := Change{
+		Time:   time.Now(),
+		Actor:  j.Actor,
+		Op:     op,
+		Key:    key,
+		Batch:  j.batch,
+		before: before,
+		after:  copyField(f.fields[key]),
+	}
+	if renderField(c.before) == renderField(c.after) {
+		return
+	}
+	c.Old, c.New = f.renderLogged(key, c.before), f.renderLogged(key, c.after)
+
+	j.changes = append(j.changes[:len(j.changes)-j.undone], c)
+	j.undone = 0
+	j.write(c)
+}
+
+// snapshot returns a copy of key for record, or nil without a journal.
+func (f *Config) snapshot(key string) ConfigEntryInterface {
+	if f.journal == nil {
+		return nil
+	}
+	return copyField(f.fields[key])
+}
+
+// renderLogged renders a version of key for the journal, redacted if the
+// key is secret.
+func (f *Config) renderLogged(key string, e ConfigEntryInterface) string {
+	if f.IsSecret(key) {
+		e = redactField(e)
+	}
+	return renderField(e)
+}
+
+func renderField(e ConfigEntryInterface) string {
+	switch v := e.(type) {
+	case *ConfigEntry:
+		return v.String()
+	case *ConfigMultiEntry:
+		lines := make([]string, 0, len(v.Entries))
+		for _, value := range v.sortedValues() {
+			lines = append(lines, v.Entries[value].String())
+		}
+		return strings.Join(lines, "\n")
+	}
+	return ""
+}
+
+// Undo reverts the last change in effect, or all the changes of the last
+// Update. It reports whether there was one.
+func (f *Config) Undo() bool {
+	j := f.journal
+	if j == nil || j.undone == len(j.changes) {
+		return false
+	}
+	for {
+		j.undone++
+		c := j.changes[len(j.changes)-j.undone]
+		f.restore(c.Key, c.before)
+		j.write(Change{Time: time.Now(), Actor: j.Actor, Op: "undo", Key: c.Key, Old: c.New, New: c.Old, Batch: c.Batch})
+		if c.Batch == 0 || j.undone == len(j.changes) || j.changes[len(j.changes)-j.undone-1].Batch != c.Batch {
+			return true
+		}
+	}
+}
+
+// Redo applies the last undone change, or all the changes of an Update,
+// again. It reports whether there was one.
+func (f *Config) Redo() bool {
+	j := f.journal
+	if j == nil || j.undone == 0 {
+		return false
+	}
+	for {
+		c := j.changes[len(j.changes)-j.undone]
+		j.undone--
+		f.restore(c.Key, c.after)
+		j.write(Change{Time: time.Now(), Actor: j.Actor, Op: "redo", Key: c.Key, Old: c.Old, New: c.New, Batch: c.Batch})
+		if c.Batch == 0 || j.undone == 0 || j.changes[len(j.changes)-j.undone].Batch != c.Batch {
+			return true
+		}
+	}
+}
+
+func (f *Config) restore(key string, e ConfigEntryInterface) {
+	if e == nil {
+		delete(f.fields, key)
+		return
+	}
+	f.fields[key] = copyField(e)
+	f.convertKey(key)
+}
@@ -0,0 +1,142 @@
+package ggo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_JournalUndoRedo(t *testing.T) {
+	c := NewConfig()
+	c.SetKeyMultiple("sync.neighbour", true)
+	c.FromString("vlan 10\nsync.neighbour 10.0.0.1\nsync.neighbour 10.0.0.2")
+	original := c.String()
+
+	j := c.EnableJournal()
+	j.Actor = "alice"
+	var log bytes.Buffer
+	j.LogTo(&log)
+
+	c.Set(ParseString("vlan 20"))
+	c.DeleteValue("sync.neighbour", "10.0.0.1")
+	c.Replace(ParseString("sync.neighbour 10.0.0.2 # primary"))
+	c.Delete("vlan")
+	edited := c.String()
+
+	changes := j.Changes()
+	if len(changes) != 4 {
+		t.Fatalf("%d changes recorded\n", len(changes))
+	}
+	if ch := changes[0]; ch.Op != "set" || ch.Key != "vlan" || ch.Old != "vlan 10" || ch.New != "vlan 20" || ch.Actor != "alice" || ch.Time.IsZero() {
+		t.Errorf("unexpected change %+v\n", ch)
+	}
+	if ch := changes[1]; ch.Old != "sync.neighbour 10.0.0.1\nsync.neighbour 10.0.0.2" || ch.New != "sync.neighbour 10.0.0.2" {
+		t.Errorf("unexpected change %+v\n", ch)
+	}
+	if ch := changes[3]; ch.Op != "delete" || ch.New != "" {
+		t.Errorf("unexpected change %+v\n", ch)
+	}
+
+	for c.Undo() {
+	}
+	if s := c.String(); s != original {
+		t.Errorf("undo left %q\n", s)
+	}
+	for c.Redo() {
+	}
+	if s := c.String(); s != edited {
+		t.Errorf("redo left %q\n", s)
+	}
+
+	c.Undo()
+	c.Set(ParseString("vlan 30"))
+	if c.Redo() {
+		t.Errorf("redo after a new change\n")
+	}
+	if n := len(j.Changes()); n != 4 {
+		t.Errorf("%d changes in effect, expected 4\n", n)
+	}
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 4+4+4+2 {
+		t.Errorf("%d log lines\n", len(lines))
+	}
+	var undo Change
+	if err := json.Unmarshal([]byte(lines[4]), &undo); err != nil || undo.Op != "undo" || undo.Key != "vlan" || undo.New != "vlan 20" {
+		t.Errorf("unexpected log line %s\n", lines[4])
+	}
+}
+
+func Test_JournalUpdate(t *testing.T) {
+	c := NewConfig()
+	c.FromString("vlan 10\nname edge")
+	j := c.EnableJournal()
+
+	c.Update(func(tx *Tx) error {
+		tx.Set(ParseString("vlan 20"))
+		tx.Set(ParseString("mtu 9000"))
+		return nil
+	})
+	changes := j.Changes()
+	if len(changes) != 2 || changes[0].Key != "mtu" || changes[0].Old != "" || changes[1].Key != "vlan" {
+		t.Errorf("unexpected changes %+v\n", changes)
+	}
+
+	if changes[0].Batch == 0 || changes[1].Batch != changes[0].Batch {
+		t.Errorf("changes not batched %+v\n", changes)
+	}
+
+	c.Set(ParseString("name core"))
+	c.Undo()
+	c.Undo()
+	if s := c.String(); s != "name edge\nvlan 10" {
+		t.Errorf("undo left %q\n", s)
+	}
+	if c.Undo() {
+		t.Errorf("undo past the update\n")
+	}
+	c.Redo()
+	if s := c.String(); s != "mtu 9000\nname edge\nvlan 20" {
+		t.Errorf("redo left %q\n", s)
+	}
+}
+
+// failingWriter fails every write after the first n.
+type failingWriter struct {
+	n int
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errDiskFull
+	}
+	w.n--
+	return len(p), nil
+}
+
+func Test_JournalLogError(t *testing.T) {
+	c := NewConfig()
+	c.FromString("vlan 10")
+	j := c.EnableJournal()
+	j.LogTo(&failingWriter{n: 1})
+
+	c.Set(ParseString("vlan 20"))
+	if j.Err() != nil {
+		t.Errorf("unexpected error %v\n", j.Err())
+	}
+	c.Set(ParseString("vlan 30"))
+	c.Undo()
+	if !errors.Is(j.Err(), errDiskFull) {
+		t.Errorf("log error is %v\n", j.Err())
+	}
+	if !errors.Is(j.Close(), errDiskFull) {
+		t.Errorf("Close did not return the log error\n")
+	}
+	if len(j.Changes()) != 1 {
+		t.Errorf("changes are %+v\n", j.Changes())
+	}
+}
@@ -0,0 +1,117 @@
+package ggo
+
+import "strings"
+
+// Layout selects how String and Write group keys.
+type Layout int
+
+const (
+	// LayoutFlat writes every key under its full dotted name.
+	LayoutFlat Layout = iota
+	// LayoutSections factors common prefixes into `[prefix]` headers.
+	LayoutSections
+	// LayoutBlocks factors common prefixes into `prefix { ... }` blocks.
+	LayoutBlocks
+)
+
+// SetLayout selects the layout used by String and Write. Keys are grouped
+// by their first depth dotted segments; groups holding a single key are
+// written flat.
+func (f *Config) SetLayout(layout Layout, depth int) {
+	f.layout = layout
+	f.layoutDepth = depth
+}
+
+type keyGroup struct {
+	prefix string
+	keys   []string
+}
+
+func groupPrefix(name string, depth int) string {
+	if depth <= 0 {
+		return ""
+	}
+	parts := strings.Split(name, ".")
+	if len(parts) <= depth {
+		return ""
+	}
+	return strings.Join(parts[:depth], ".")
+}
+
+// groups splits sorted keys into prefix groups. Ungrouped keys come first
+// under an empty prefix.
+func (f *Config) groups() []keyGroup {
+	byPrefix := make(map[string][]string)
+	prefixes := make([]string, 0)
+	for _, k := range f.sortedKeys() {
+		p := groupPrefix(k, f.layoutDepth)
+		if _, exists := byPrefix[p]; !exists {
+			prefixes = append(prefixes, p)
+		}
+		byPrefix[p] = append(byPrefix[p], k)
+	}
+
+	flat := keyGroup{}
+	res := make([]keyGroup, 0, len(prefixes))
+	for _, p := range prefixes {
+		keys := byPrefix[p]
+		if p == "" || len(keys) < 2 {
+			flat.keys = append(flat.keys, keys...)
+			continue
+		}
+		res = append(res, keyGroup{prefix: p, keys: keys})
+	}
+	return append([]keyGroup{flat}, res...)
+}
+
+// entryLines renders e under name, one line per value preceded by its doc
+// comment, wrapped and indented.
+func (f *Config) entryLines(e ConfigEntryInterface, name string, indent string) []string {
+	entries := entriesOf(e)
+	res := make([]string, 0, len(entries))
+	for _, v := range entries {
+		if v.DocComment != "" {
+			for _, doc := range f.commentLines(v.DocComment) {
+				res = append(res, indent+doc)
+			}
+		}
+		line := v.format(name)
+		if f.strict && !v.IsActive {
+			line = disabledMarker + strings.TrimPrefix(line, "# ")
+		}
+		for _, line := range wrapLine(line, f.wrapWidth-len(indent)) {
+			res = append(res, indent+line)
+		}
+	}
+	return res
+}
+
+func (f *Config) groupedLines() []string {
+	res := make([]string, 0, len(f.fields))
+	for _, g := range f.groups() {
+		if g.prefix == "" {
+			for _, k := range g.keys {
+				res = append(res, f.entryLines(f.fields[k], k, "")...)
+			}
+			continue
+		}
+
+		if len(res) > 0 {
+			res = append(res, "")
+		}
+		indent := ""
+		if f.layout == LayoutBlocks {
+			res = append(res, g.prefix+" {")
+			indent = "\t"
+		} else {
+			res = append(res, "["+g.prefix+"]")
+		}
+		for _, k := range g.keys {
+			res = append(res, f.entryLines(f.fields[k], k[len(g.prefix)+1:], indent)...)
+		}
+		if f.layout == LayoutBlocks {
+			res = append(res, "}")
+		}
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+package ggo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diagnostic is a problem found at a line of a config document.
+type Diagnostic struct {
+	Line    int
+	Key     string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Key == "" {
+		return fmt.Sprintf("line %d: %s", d.Line, d.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", d.Line, d.Key, d.Message)
+}
+
+// canonicalKey folds the spelling variations Lint warns about.
+func canonicalKey(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+// looksLikeKey tells dotted or hyphenated key names from prose words.
+func looksLikeKey(name string) bool {
+	return strings.ContainsAny(name, ".-_")
+}
+
+// Lint parses doc the way scheme would and reports what parsing silently
+// resolves or drops: repeated active single keys, repeated values of
+// multi-valued keys, keys present both active and commented out, keys
+// missing from the schema or deprecated by it, keys differing only in case
+// or in `_` versus `-`, trailing whitespace, and commented lines that look
+// like entries but are read as comments. scheme provides key multiplicity,
+// the schema and comment mode; it may be nil.
+func Lint(doc []byte, scheme *Config) []Diagnostic {
+	if scheme == nil {
+		scheme = NewConfig()
+	}
+	conf := scheme.CopyScheme()
+
+	var res []Diagnostic
+	report := func(line int, key string, format string, args ...interface{}) {
+		res = append(res, Diagnostic{Line: line, Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	text := strings.ReplaceAll(string(doc), "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		if strings.TrimRight(line, " \t") != line {
+			report(i+1, "", "trailing whitespace")
+		}
+	}
+
+	var entries []*ConfigEntry
+	p := newParser(conf)
+	p.emit = func(e *ConfigEntry) {
+		entries = append(entries, e)
+	}
+	p.onComment = func(line string) {
+		if e := ParseString(line); e != nil && looksLikeKey(e.Name()) {
+			report(p.start, e.Name(), "looks like a commented-out entry but is read as a comment")
+		}
+	}
+	for _, line := range lines {
+		p.feed(line)
+	}
+	p.finish()
+	res = append(res, conf.diagnostics...)
+
+	active := make(map[string]*ConfigEntry)
+	inactive := make(map[string]*ConfigEntry)
+	values := make(map[string]map[string]*ConfigEntry)
+	spelling := make(map[string]string)
+
+	for _, e := range entries {
+		name := e.Name()
+
+		if conf.schema != nil && conf.schema.Lookup(name) == nil {
+			report(e.line, name, "unknown key")
+		}
+
+		canonical := canonicalKey(name)
+		if first, exists := spelling[canonical]; !exists {
+			spelling[canonical] = name
+		} else if first != name {
+			report(e.line, name, "differs from '%s' only in case or '_'/'-'", first)
+		}
+
+		if conf.isMultiple(name) {
+			if values[name] == nil {
+				values[name] = make(map[string]*ConfigEntry)
+			}
+			if prev, exists := values[name][e.Value]; exists {
+				report(e.line, name, "value '%s' repeats line %d", e.Value, prev.line)
+			} else {
+				values[name][e.Value] = e
+			}
+			continue
+		}
+
+		if e.IsActive {
+			if prev, exists := active[name]; exists {
+				report(e.line, name, "duplicate active entry, overrides line %d", prev.line)
+			} else if prev, exists := inactive[name]; exists {
+				report(e.line, name, "active entry also commented out at line %d", prev.line)
+			}
+			active[name] = e
+		} else {
+			if prev, exists := active[name]; exists {
+				report(e.line, name, "commented-out entry also active at line %d", prev.line)
+			}
+			if _, exists := inactive[name]; !exists {
+				inactive[name] = e
+			}
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Line < res[j].Line
+	})
+	return res
+}
@@ -0,0 +1,46 @@
+package ggo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	doc := "pcap-speed 220\n" +
+		"pcap-speed 240 \n" +
+		"sync 239.0.0.3\n" +
+		"#sync 239.0.0.3\n" +
+		"#sflow.drop.pool 0\n" +
+		"sflow.drop.pool 1\n" +
+		"Pcap_Speed 1\n" +
+		"# see docs\n" +
+		"#sflow.drop.poll 0\n" +
+		"unknown.key 1\n"
+
+	scheme := NewConfig()
+	scheme.SetSchema(NewSchema(
+		&KeySpec{Name: "pcap-speed"},
+		&KeySpec{Name: "Pcap_Speed"},
+		&KeySpec{Name: "sync", Multiple: true},
+		&KeySpec{Name: "sflow.*.pool"},
+	))
+
+	expected := []Diagnostic{
+		{2, "", "trailing whitespace"},
+		{2, "pcap-speed", "duplicate active entry, overrides line 1"},
+		{4, "sync", "value '239.0.0.3' repeats line 3"},
+		{6, "sflow.drop.pool", "active entry also commented out at line 5"},
+		{7, "Pcap_Speed", "differs from 'pcap-speed' only in case or '_'/'-'"},
+		{9, "sflow.drop.poll", "looks like a commented-out entry but is read as a comment"},
+		{10, "unknown.key", "unknown key"},
+	}
+
+	got := Lint([]byte(doc), scheme)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected diagnostics:\n%v\nexpected:\n%v\n", got, expected)
+	}
+
+	if got := Lint([]byte("a 1\n#a 2\n"), nil); len(got) != 1 || got[0].String() != "line 2: a: commented-out entry also active at line 1" {
+		t.Errorf("unexpected diagnostics without scheme: %v\n", got)
+	}
+}
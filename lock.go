@@ -0,0 +1,175 @@
+package ggo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLockUnsupported is returned by the locking functions on systems
+// without flock.
+var ErrLockUnsupported = errors.New("file locking is not supported on this system")
+
+// ErrConflict is returned when the file a config was read from changed
+// since it was read.
+var ErrConflict = errors.New("file changed since it was read")
+
+// Locks are advisory: they only exclude writers that lock too. They are
+// taken on a `<file>.lock` file next to the config, since writes replace
+// the config file itself.
+func lockPath(path string) string {
+	return path + ".lock"
+}
+
+// lock takes a lock for path and returns the function releasing it.
+func lock(path string, exclusive bool) (func(), error) {
+	file, err := os.OpenFile(lockPath(path), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := flock(file, exclusive); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() {
+		funlock(file)
+		file.Close()
+	}, nil
+}
+
+// fileStamp identifies the version of a file a config was read from.
+type fileStamp struct {
+	modTime time.Time
+	inode   uint64
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+func newStamp(info os.FileInfo, data []byte) *fileStamp {
+	return &fileStamp{
+		modTime: info.ModTime(),
+		inode:   inode(info),
+		size:    info.Size(),
+		sum:     sha256.Sum256(data),
+	}
+}
+
+// checkUnchanged returns ErrConflict if the file at path is not the one
+// the config was read from. A file with a new time or inode but the same
+// content is unchanged.
+func (f *Config) checkUnchanged(path string) error {
+	if f.stamp == nil || path != f.path {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s: %w", path, ErrConflict)
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(f.stamp.modTime) && inode(info) == f.stamp.inode && info.Size() == f.stamp.size {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if sum := sha256.Sum256(data); !bytes.Equal(sum[:], f.stamp.sum[:]) {
+		return fmt.Errorf("%s: %w", path, ErrConflict)
+	}
+	return nil
+}
+
+// save writes the config atomically to path, failing with ErrConflict if
+// the file it was read from changed meanwhile, and remembers the written
+// file as the one read.
+func (f *Config) save(path string) error {
+	if err := f.checkUnchanged(path); err != nil {
+		return err
+	}
+	data := f.fileContent()
+	if err := writeAtomic(path, data); err != nil {
+		return err
+	}
+	f.path = path
+	f.stamp = nil
+	if info, err := os.Stat(path); err == nil {
+		f.stamp = newStamp(info, data)
+	}
+	return nil
+}
+
+// Save writes the config to the file it was read from, through a
+// temporary file renamed over it. It fails with ErrConflict, writing
+// nothing, if the file changed since it was read or saved; without locks
+// a change made between the check and the rename still goes unnoticed.
+func (f *Config) Save() error {
+	if f.path == "" {
+		return errors.New("config has no path")
+	}
+	return f.save(f.path)
+}
+
+// LoadLocked reads the file at path under a shared lock.
+func (f *Config) LoadLocked(path string) error {
+	unlock, err := lock(path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return f.FromFile(file)
+}
+
+// WriteLocked writes the config to path like Save, under an exclusive
+// lock.
+func (f *Config) WriteLocked(path string) error {
+	unlock, err := lock(path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return f.save(path)
+}
+
+// WithLock reads the file at path into a copy of f's scheme, passes it to
+// fn and, if fn succeeds, writes it back with f's layout, wrap width and
+// checksum setting, holding an exclusive lock all along. The copy also
+// takes f's secret key. A missing file is read as an empty config.
+func (f *Config) WithLock(path string, fn func(cfg *Config) error) error {
+	unlock, err := lock(path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg := f.copySettings()
+	if file, err := os.Open(path); err == nil {
+		err = cfg.FromFile(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := fn(cfg); err != nil {
+		return err
+	}
+	return cfg.save(path)
+}
+
+// WithLock is Config.WithLock for configs without a schema.
+func WithLock(path string, fn func(cfg *Config) error) error {
+	return NewConfig().WithLock(path, fn)
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package ggo
+
+import "os"
+
+func flock(file *os.File, exclusive bool) error {
+	return ErrLockUnsupported
+}
+
+func funlock(file *os.File) error {
+	return ErrLockUnsupported
+}
+
+func inode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,109 @@
+//go:build linux
+
+package ggo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_WithLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ggo.conf")
+
+	// Concurrent read-modify-write cycles must not lose increments.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := WithLock(path, func(cfg *Config) error {
+				n, _ := cfg.GetInt("counter")
+				cfg.Set(ParseString("counter " + strconv.FormatInt(n+1, 10)))
+				return nil
+			})
+			if err != nil {
+				t.Errorf("WithLock failed: %v\n", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	c := NewConfig()
+	if err := c.LoadLocked(path); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.GetInt("counter"); n != 20 {
+		t.Errorf("counter is %d, expected 20\n", n)
+	}
+
+	failure := errors.New("abort")
+	err := WithLock(path, func(cfg *Config) error {
+		cfg.Set(ParseString("counter 0"))
+		return failure
+	})
+	if err != failure {
+		t.Errorf("WithLock = %v\n", err)
+	}
+	c.LoadLocked(path)
+	if n, _ := c.GetInt("counter"); n != 20 {
+		t.Errorf("failed edit written\n")
+	}
+
+	c.SetLayout(LayoutSections, 1)
+	c.WithLock(path, func(cfg *Config) error {
+		cfg.Set(ParseString("tb.mtu 1500"))
+		cfg.Set(ParseString("tb.vlan 10"))
+		return nil
+	})
+	if data, _ := os.ReadFile(path); string(data) != "counter 20\n\n[tb]\nmtu 1500\nvlan 10\n" {
+		t.Errorf("layout not kept: %q\n", data)
+	}
+}
+
+func Test_SaveConflict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ggo.conf")
+	os.WriteFile(path, []byte("vlan 10\n"), 0644)
+
+	a, b := NewConfig(), NewConfig()
+	a.LoadLocked(path)
+	b.LoadLocked(path)
+
+	a.Set(ParseString("vlan 20"))
+	if err := a.Save(); err != nil {
+		t.Fatalf("Save failed: %v\n", err)
+	}
+	// a saved, so its next save does not conflict with itself.
+	a.Set(ParseString("mtu 9000"))
+	if err := a.WriteLocked(path); err != nil {
+		t.Errorf("second save failed: %v\n", err)
+	}
+
+	b.Set(ParseString("vlan 30"))
+	if err := b.Save(); !errors.Is(err, ErrConflict) {
+		t.Errorf("lost update not detected: %v\n", err)
+	}
+	err := b.Update(func(tx *Tx) error {
+		tx.Set(ParseString("vlan 40"))
+		return nil
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("Update did not detect the conflict: %v\n", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "mtu 9000\nvlan 20\n" {
+		t.Errorf("file is %q\n", data)
+	}
+
+	// Touching the file without changing it is not a conflict.
+	c := NewConfig()
+	c.LoadLocked(path)
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(path, later, later)
+	if err := c.Save(); err != nil {
+		t.Errorf("unchanged content reported as a conflict: %v\n", err)
+	}
+}
@@ -0,0 +1,195 @@
+package ggo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MigrationRule renames the key From to To. Value, if set, converts each
+// value to its new format.
+type MigrationRule struct {
+	From  string
+	To    string
+	Value func(value string) (string, error)
+}
+
+func (r *MigrationRule) convert(value string) (string, error) {
+	if r.Value == nil {
+		return value, nil
+	}
+	return r.Value(value)
+}
+
+// MigrationRules returns a rule for every alias declared in the schema.
+// Values of typed keys are converted to their canonical form.
+func (s *Schema) MigrationRules() []MigrationRule {
+	var res []MigrationRule
+	for _, k := range s.keys {
+		for _, alias := range k.Aliases {
+			r := MigrationRule{From: alias, To: k.Name}
+			if t := k.Type; t != nil {
+				r.Value = func(value string) (string, error) {
+					parsed, err := t.Parse(unquote(value))
+					if err != nil {
+						return "", err
+					}
+					return t.Format(parsed), nil
+				}
+			}
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// Migrate applies rules to cfg. Renamed entries join any entries already
+// stored under the new name the way duplicates do when parsing. A From key
+// that the schema declares as an alias was already stored under its
+// canonical name when cfg was read; the values stored there are converted.
+// On error cfg is left unchanged.
+func Migrate(cfg *Config, rules []MigrationRule) error {
+	var migrated []*ConfigEntry
+	for i := range rules {
+		r := &rules[i]
+		var entries []*ConfigEntry
+		switch v := cfg.fields[cfg.canonicalName(r.From)].(type) {
+		case *ConfigEntry:
+			entries = []*ConfigEntry{v}
+		case *ConfigMultiEntry:
+			for _, value := range v.sortedValues() {
+				entries = append(entries, v.Entries[value])
+			}
+		}
+
+		for _, e := range entries {
+			value, err := r.convert(e.Value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", r.From, err)
+			}
+			res := e.Copy().(*ConfigEntry)
+			res.name = r.To
+			res.Value = value
+			migrated = append(migrated, res)
+		}
+	}
+
+	for i := range rules {
+		delete(cfg.fields, cfg.canonicalName(rules[i].From))
+	}
+	for _, e := range migrated {
+		cfg.setWhileParsing(e)
+	}
+	return nil
+}
+
+// MigrateDocument applies rules to the entries of doc, read the way scheme
+// reads them, rewriting only the key and value of each migrated line so
+// that layout, spacing and comments are preserved. Entries whose tokens
+// cannot be rewritten in place, such as values continued over several
+// lines, are written again on a single line. A checksum trailer is
+// recomputed for the migrated content. scheme may be nil.
+func MigrateDocument(doc []byte, scheme *Config, rules []MigrationRule) ([]byte, error) {
+	if scheme == nil {
+		scheme = NewConfig()
+	}
+	byName := make(map[string]*MigrationRule, len(rules))
+	for i := range rules {
+		byName[rules[i].From] = &rules[i]
+	}
+
+	lines := strings.Split(string(doc), "\n")
+	conf := scheme.CopyScheme()
+	p := newParser(conf)
+	drop := make(map[int]bool)
+	var errs []string
+	p.emit = func(e *ConfigEntry) {
+		r, exists := byName[p.written]
+		if !exists {
+			return
+		}
+
+		scope := strings.TrimSuffix(strings.TrimSuffix(p.written, p.short), ".")
+		key := r.To
+		if scope != "" {
+			if !strings.HasPrefix(r.To, scope+".") {
+				errs = append(errs, fmt.Sprintf("line %d: cannot move %s out of %s", p.start, p.written, scope))
+				return
+			}
+			key = strings.TrimPrefix(r.To, scope+".")
+		}
+
+		value, err := r.convert(e.Value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: %s: %v", p.start, p.written, err))
+			return
+		}
+		// The rewritten line must read back as the migrated entry.
+		migrated := func(line string) bool {
+			res := conf.parseLine(strings.TrimSpace(line), func(name string) string {
+				return strings.TrimPrefix(scope+"."+name, ".")
+			})
+			return res != nil && res.name == conf.canonicalName(r.To) && res.Value == value && res.IsActive == e.IsActive
+		}
+
+		first := lines[p.start-1]
+		if p.line == p.start {
+			if line := rewriteEntryLine(first, p.short, key, e.Value, value); migrated(line) {
+				lines[p.start-1] = line
+				return
+			}
+		}
+
+		res := e.Copy().(*ConfigEntry)
+		res.Value = value
+		line := res.format(key)
+		if isDisabledMarked(strings.TrimSpace(first)) {
+			line = disabledMarker + strings.TrimPrefix(line, "# ")
+		}
+		line = first[:len(first)-len(strings.TrimLeft(first, " \t"))] + line
+		if strings.HasSuffix(lines[p.line-1], "\r") {
+			line += "\r"
+		}
+		if !migrated(line) {
+			errs = append(errs, fmt.Sprintf("line %d: cannot rewrite %s", p.start, p.written))
+			return
+		}
+		lines[p.start-1] = line
+		for i := p.start; i < p.line; i++ {
+			drop[i] = true
+		}
+	}
+	for _, line := range lines {
+		p.feed(line)
+	}
+	p.finish()
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	res := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if !drop[i] {
+			res = append(res, line)
+		}
+	}
+	return reseal([]byte(strings.Join(res, "\n"))), nil
+}
+
+// rewriteEntryLine replaces the key and value tokens of a physical entry
+// line in place.
+func rewriteEntryLine(line string, key string, newKey string, value string, newValue string) string {
+	i := len(line) - len(strings.TrimLeft(line, " \t#-"))
+	if !strings.HasPrefix(line[i:], key) {
+		i = strings.Index(line, key)
+	}
+	if i < 0 {
+		return line
+	}
+	head, tail := line[:i]+newKey, line[i+len(key):]
+	if value != newValue {
+		if j := strings.Index(tail, value); j >= 0 {
+			tail = tail[:j] + newValue + tail[j+len(value):]
+		}
+	}
+	return head + tail
+}
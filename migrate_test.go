@@ -0,0 +1,152 @@
+package ggo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func aliasSchema() *Schema {
+	return NewSchema(
+		&KeySpec{Name: "pcap.speed", Aliases: []string{"pcap-speed"}, Type: TypeRate},
+		&KeySpec{Name: "sync.neighbour", Aliases: []string{"sync-neighbour"}, Multiple: true},
+		&KeySpec{Name: "sflow.drop.pool", Aliases: []string{"sflow.drop.pool_size"}},
+	)
+}
+
+func TestConfig_Aliases(t *testing.T) {
+	file := NewConfig()
+	file.SetSchema(aliasSchema())
+	file.FromStrings([]string{
+		"pcap-speed 220000",
+		"sync-neighbour 198.18.1.3",
+		"sync.neighbour 198.18.1.1",
+		"#sflow.drop.pool_size 0",
+	})
+
+	expected := []Diagnostic{
+		{1, "pcap-speed", "deprecated key, use 'pcap.speed'"},
+		{2, "sync-neighbour", "deprecated key, use 'sync.neighbour'"},
+		{4, "sflow.drop.pool_size", "deprecated key, use 'sflow.drop.pool'"},
+	}
+	if !reflect.DeepEqual(file.Diagnostics(), expected) {
+		t.Errorf("unexpected diagnostics %v\n", file.Diagnostics())
+	}
+
+	if file.Get("pcap-speed") != file.Get("pcap.speed") || file.Get("pcap.speed") == nil {
+		t.Error("Get does not resolve aliases")
+	}
+
+	file.Set(ParseString("pcap-speed 240"))
+	update := NewConfig()
+	update.FromStrings([]string{"sync-neighbour 198.18.1.8", "sflow.drop.pool_size 1"})
+	merged := Merge(file, update)
+
+	merged.checkEntry(t, true, "pcap-speed", "240", "")
+	merged.checkEntry(t, true, "sflow.drop.pool", "1", "")
+	merged.checkMultiEntry(t, "sync.neighbour", map[string]bool{
+		"198.18.1.1": true,
+		"198.18.1.3": true,
+		"198.18.1.8": true,
+	})
+	if merged.Len() != 0 {
+		t.Errorf("Some fields (%d) left unprocessed %v\n", merged.Len(), merged.fields)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	file := NewConfig()
+	file.SetKeyMultiple("sync-neighbour", true)
+	file.SetKeyMultiple("sync.neighbour", true)
+	file.FromStrings([]string{
+		"pcap-speed 220000",
+		"sync-neighbour 198.18.1.3",
+		"sync.neighbour 198.18.1.1",
+	})
+
+	if err := Migrate(file, aliasSchema().MigrationRules()); err != nil {
+		t.Fatalf("Migrate failed: %v\n", err)
+	}
+	file.checkEntry(t, true, "pcap.speed", "220k", "")
+	file.checkMultiEntry(t, "sync.neighbour", map[string]bool{"198.18.1.1": true, "198.18.1.3": true})
+	if file.Len() != 0 {
+		t.Errorf("Some fields (%d) left unprocessed %v\n", file.Len(), file.fields)
+	}
+
+	file.FromStrings([]string{"pcap-speed fast", "sync-neighbour 198.18.1.3"})
+	if err := Migrate(file, aliasSchema().MigrationRules()); err == nil || file.Get("sync-neighbour") == nil {
+		t.Errorf("Migrate accepted an invalid value or modified the config: %v\n", err)
+	}
+}
+
+func TestMigrateDocument(t *testing.T) {
+	doc := "# capture\n" +
+		"pcap-speed\t\t220000   # pps\n" +
+		"sflow.drop {\n" +
+		"\t#pool_size  0\n" +
+		"}\n" +
+		"sync-neighbour 198.18.1.3\n"
+
+	expected := "# capture\n" +
+		"pcap.speed\t\t220k   # pps\n" +
+		"sflow.drop {\n" +
+		"\t#pool  0\n" +
+		"}\n" +
+		"sync.neighbour 198.18.1.3\n"
+
+	got, err := MigrateDocument([]byte(doc), nil, aliasSchema().MigrationRules())
+	if err != nil || string(got) != expected {
+		t.Errorf("unexpected migration (%v):\n%s\n", err, got)
+	}
+
+	scheme := NewConfig()
+	scheme.SetSchema(aliasSchema())
+	got, err = MigrateDocument([]byte(doc), scheme, aliasSchema().MigrationRules())
+	if err != nil || string(got) != expected {
+		t.Errorf("unexpected migration with schema (%v):\n%s\n", err, got)
+	}
+
+	rules := []MigrationRule{{From: "sflow.drop.pool_size", To: "sflow.pool"}}
+	if _, err := MigrateDocument([]byte(doc), nil, rules); err == nil {
+		t.Error("MigrateDocument moved a key out of its block")
+	}
+}
+
+func TestMigrateDocument_Continued(t *testing.T) {
+	doc := "pcap-speed \\\n" +
+		"\t220000\n" +
+		"sflow.drop {\n" +
+		"\t#- pool_size 0,\\\n" +
+		"\t#\t1   # pools\n" +
+		"}\n" +
+		"vlan 10\n"
+
+	expected := "pcap.speed 220k\n" +
+		"sflow.drop {\n" +
+		"\t#-pool 0,1 # pools\n" +
+		"}\n" +
+		"vlan 10\n"
+
+	got, err := MigrateDocument([]byte(doc), nil, aliasSchema().MigrationRules())
+	if err != nil || string(got) != expected {
+		t.Errorf("unexpected migration (%v):\n%s\n", err, got)
+	}
+}
+
+func TestMigrate_Aliased(t *testing.T) {
+	file := NewConfig()
+	file.SetSchema(aliasSchema())
+	file.FromStrings([]string{
+		"pcap-speed 220000",
+		"sync-neighbour 198.18.1.3",
+		"sync.neighbour 198.18.1.1",
+	})
+
+	if err := Migrate(file, aliasSchema().MigrationRules()); err != nil {
+		t.Fatalf("Migrate failed: %v\n", err)
+	}
+	file.checkEntry(t, true, "pcap.speed", "220k", "")
+	file.checkMultiEntry(t, "sync.neighbour", map[string]bool{"198.18.1.1": true, "198.18.1.3": true})
+	if file.Len() != 0 {
+		t.Errorf("Some fields (%d) left unprocessed %v\n", file.Len(), file.fields)
+	}
+}
@@ -0,0 +1,155 @@
+package ggo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_SetMultiple(t *testing.T) {
+	c := NewConfig()
+	c.SetKeyMultiple("sync-neighbour", true)
+	c.FromString("sync-neighbour 10.0.0.1\nsync-neighbour 10.0.0.2 # backup\nmode edge")
+
+	c.Set(NewEntry("sync-neighbour", "10.0.0.3"))
+	c.Set(NewEntry("sync-neighbour", "10.0.0.2", WithComment("primary")))
+	c.Set(NewEntry("mode", "core"))
+
+	expected := `mode core
+sync-neighbour 10.0.0.1
+sync-neighbour 10.0.0.2 # primary
+sync-neighbour 10.0.0.3`
+	if s := c.String(); s != expected {
+		t.Errorf("config is\n%s\n", s)
+	}
+	c.checkEntry(t, true, "mode", "core", "")
+	c.checkMultiEntry(t, "sync-neighbour", map[string]bool{"10.0.0.1": true, "10.0.0.2": true, "10.0.0.3": true})
+}
+
+func Test_AddSetAll(t *testing.T) {
+	c := NewConfig()
+	c.SetKeyMultiple("sync", true)
+	c.FromString("sync 239.0.0.1\nsync 239.0.0.2\nmode edge")
+	c.EnableJournal()
+
+	if err := c.Add(NewEntry("sync", "239.0.0.3")); err != nil {
+		t.Errorf("Add: %v\n", err)
+	}
+	if err := c.Add(NewEntry("mode", "core")); !errors.Is(err, ErrNotMultiple) {
+		t.Errorf("Add to a single-valued key: %v\n", err)
+	}
+	if err := c.Add(NewEntry("port", "1")); !errors.Is(err, ErrNotMultiple) {
+		t.Errorf("Add to a new single-valued key: %v\n", err)
+	}
+	if c.Get("mode").(*ConfigEntry).Value != "edge" || c.Get("port") != nil {
+		t.Errorf("failed Add changed the config\n")
+	}
+	if m, ok := c.Get("sync").(*ConfigMultiEntry); !ok || len(m.Entries) != 3 {
+		t.Errorf("sync is %v\n", c.Get("sync"))
+	}
+
+	if err := c.SetAll("sync", NewEntry("sync", "239.1.0.1"), NewEntry("other", "239.1.0.2")); err != nil {
+		t.Errorf("SetAll: %v\n", err)
+	}
+	if v := c.Get("sync").(*ConfigMultiEntry).Values(); strings.Join(v, " ") != "239.1.0.1 239.1.0.2" {
+		t.Errorf("sync is %v\n", v)
+	}
+	if err := c.SetAll("mode", NewEntry("mode", "a"), NewEntry("mode", "b")); !errors.Is(err, ErrNotMultiple) {
+		t.Errorf("SetAll of two values to a single-valued key: %v\n", err)
+	}
+	c.SetAll("mode", NewEntry("mode", "core"))
+
+	c.Undo()
+	c.Undo()
+	if v := c.Get("sync").(*ConfigMultiEntry).Values(); strings.Join(v, " ") != "239.0.0.1 239.0.0.2 239.0.0.3" {
+		t.Errorf("sync after undo is %v\n", v)
+	}
+
+	c.SetAll("sync")
+	if c.Get("sync") != nil {
+		t.Errorf("SetAll without entries kept the key\n")
+	}
+
+	// A key declared multi-valued after its single entry was set keeps
+	// every value added to it.
+	c.SetKeyMultiple("mode", true)
+	c.Add(NewEntry("mode", "core"))
+	c.checkMultiEntry(t, "mode", map[string]bool{"edge": true, "core": true})
+}
+
+func Test_ChangeMultiplicity(t *testing.T) {
+	c := NewConfig()
+	c.FromString("sync 239.0.0.1\nsync 239.0.0.2\n#sync 239.0.0.3\nmode edge\nmode core")
+
+	c.SetKeyMultiple("sync", true)
+	if m, ok := c.Get("sync").(*ConfigMultiEntry); !ok || len(m.Entries) != 1 || m.Get("239.0.0.2") == nil {
+		t.Errorf("sync is %v\n", c.Get("sync"))
+	}
+	if err := c.Reparse(); err != nil {
+		t.Errorf("Reparse: %v\n", err)
+	}
+	c.checkEntry(t, true, "mode", "core", "")
+
+	if m, ok := c.Get("sync").(*ConfigMultiEntry); !ok || len(m.Entries) != 3 {
+		t.Errorf("sync after Reparse is %v\n", c.Get("sync"))
+	}
+
+	c.EnableJournal()
+	c.SetKeyMultiple("sync", false)
+	if changes := c.Journal().Changes(); len(changes) != 1 || changes[0].Op != "convert" || changes[0].New != "sync 239.0.0.2" {
+		t.Errorf("changes are %+v\n", changes)
+	}
+	c.checkEntry(t, true, "sync", "239.0.0.2", "")
+
+	c.SetKeyMultiple("sync", true)
+	c.Reparse()
+	// SetKeyMultiple overrides the schema.
+	c.SetSchema(NewSchema(&KeySpec{Name: "mode", Multiple: true}, &KeySpec{Name: "sync"}))
+	c.checkMultiEntry(t, "mode", map[string]bool{"core": true})
+	c.checkMultiEntry(t, "sync", map[string]bool{"239.0.0.1": true, "239.0.0.2": true, "239.0.0.3": false})
+
+	c.Set(NewEntry("mode", "core"))
+	c.SetKeyMultiple("mode", false)
+	if MergeSchemes(c).isMultiple("mode") {
+		t.Errorf("mode is multi-valued after merging\n")
+	}
+	c.checkEntry(t, true, "mode", "core", "")
+}
+
+func Test_MergeMixedMultiplicity(t *testing.T) {
+	multi := NewConfig()
+	multi.SetSchema(NewSchema(&KeySpec{Name: "sync", Multiple: true}))
+	multi.FromString("sync 239.0.0.1\nsync 239.0.0.2\n#sync 239.0.0.3")
+
+	single := NewConfig()
+	single.SetSchema(NewSchema(&KeySpec{Name: "sync"}, &KeySpec{Name: "mode"}))
+	single.FromString("mode edge")
+
+	res := Merge(multi, single)
+	res.checkEntry(t, true, "sync", "239.0.0.2", "")
+	res.checkEntry(t, true, "mode", "edge", "")
+}
+
+func Test_MultiEntryString(t *testing.T) {
+	c := NewConfig()
+	c.SetKeyMultiple("sync", true)
+	c.FromString("sync 239.0.0.3\nsync 239.0.0.1\nsync 239.0.0.2")
+
+	for i := 0; i < 20; i++ {
+		if s := c.Get("sync").String(); s != "sync 239.0.0.1\nsync 239.0.0.2\nsync 239.0.0.3" {
+			t.Errorf("sync is\n%s\n", s)
+			break
+		}
+	}
+}
+
+func Test_DeleteValueSingle(t *testing.T) {
+	c := NewConfig()
+	c.FromString("key y")
+	if e := c.DeleteValue("key", "x"); e != nil {
+		t.Errorf("deleted '%s' for another value\n", e)
+	}
+	if e := c.DeleteValue("key", "y"); e == nil || c.Get("key") != nil {
+		t.Errorf("key not deleted\n")
+	}
+}
@@ -1,5 +1,7 @@
 package ggo
 
+import "sort"
+
 type ConfigMultiEntry struct {
 	name    string
 	Entries map[string]*ConfigEntry
@@ -17,14 +19,24 @@ func (e *ConfigMultiEntry) String() string {
 	return res
 }
 
+// StringLn renders the values in sorted order, one per line.
 func (e *ConfigMultiEntry) StringLn() string {
 	res := ""
-	for _, e := range e.Entries {
-		res += e.StringLn()
+	for _, v := range e.sortedValues() {
+		res += e.Entries[v].StringLn()
 	}
 	return res
 }
 
+func (e *ConfigMultiEntry) sortedValues() []string {
+	values := make([]string, 0, len(e.Entries))
+	for v := range e.Entries {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
 func (e *ConfigMultiEntry) Get(value string) *ConfigEntry {
 	return e.Entries[value]
 }
@@ -58,11 +70,13 @@ func (e *ConfigMultiEntry) ChooseActiveOrReduce(v *ConfigEntry) ConfigEntryInter
 	return e
 }
 
+// Copy returns a deep copy of the entry, sharing no entries with it.
 func (e *ConfigMultiEntry) Copy() ConfigEntryInterface {
 	res := new(ConfigMultiEntry)
+	res.name = e.name
 	res.Entries = make(map[string]*ConfigEntry, len(e.Entries))
 	for k, v := range e.Entries {
-		res.Entries[k] = v
+		res.Entries[k] = v.Copy().(*ConfigEntry)
 	}
 
 	return res
@@ -82,11 +96,11 @@ func (e *ConfigMultiEntry) Merge(e1 ConfigEntryInterface) {
 
 	switch v := e1.(type) {
 	case *ConfigEntry:
-		e.Entries[v.Value] = v
+		e.Entries[v.Value] = inheritDoc(v.Copy().(*ConfigEntry), e.Entries[v.Value])
 
 	case *ConfigMultiEntry:
 		for _, v := range v.Entries {
-			e.Entries[v.Value] = v
+			e.Entries[v.Value] = inheritDoc(v.Copy().(*ConfigEntry), e.Entries[v.Value])
 		}
 	}
 
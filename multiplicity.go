@@ -0,0 +1,74 @@
+package ggo
+
+import "sort"
+
+// convertKey stores the entries of name the way its multiplicity requires,
+// after the multiplicity changed.
+func (f *Config) convertKey(name string) {
+	switch v := f.fields[name].(type) {
+	case *ConfigEntry:
+		if f.isMultiple(name) {
+			f.fields[name] = v.MakeMultiple()
+		}
+	case *ConfigMultiEntry:
+		if f.isMultiple(name) {
+			return
+		}
+		if e := collapse(v); e != nil {
+			f.fields[name] = e
+		} else {
+			delete(f.fields, name)
+		}
+	}
+}
+
+// collapse reduces the entries of e to the one parsing would keep had they
+// been read as duplicates of a single-valued key: the last active entry, or
+// the last one if none is active. Entries with no source line count as
+// read after the others. It returns nil for an empty entry.
+func collapse(e ConfigEntryInterface) *ConfigEntry {
+	entries := entriesOf(e)
+	if len(entries) == 0 {
+		return nil
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].line, entries[j].line
+		return a != 0 && (b == 0 || a < b)
+	})
+
+	res := entries[0]
+	for _, next := range entries[1:] {
+		res = res.ChooseActiveOrReduce(next).(*ConfigEntry)
+	}
+	return res
+}
+
+// Reparse reads the config again from the lines it was last loaded from,
+// with the current multiplicities, schema and comment mode. Unlike the
+// conversion done by SetKeyMultiple, it recovers the values of a key that
+// were collapsed into one while it was single-valued. Changes made since
+// loading are lost. Configs never loaded are left unchanged.
+func (f *Config) Reparse() error {
+	if f.raw == nil {
+		return nil
+	}
+	lines := f.raw
+	before := f.fields
+
+	f.fields = make(map[string]ConfigEntryInterface, len(before))
+	p := newParser(f)
+	for _, line := range lines {
+		p.feed(line)
+	}
+	p.finish()
+
+	if f.journal != nil {
+		for _, k := range unionKeys(before, f.fields) {
+			f.record("reparse", k, copyField(before[k]))
+		}
+	}
+	if p.badChecksum {
+		return ErrChecksum
+	}
+	return f.Upgrade()
+}
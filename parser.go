@@ -0,0 +1,334 @@
+package ggo
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// parser joins physical lines into logical ones and feeds parsed entries
+// into a Config.
+//
+// A line ending with an odd number of backslashes continues on the next
+// line: the backslash is dropped and the next line is appended with its
+// leading whitespace removed. Continuation lines of a commented-out entry
+// may repeat the '#' marker.
+//
+// Keys may be grouped either under a `[prefix]` section header, which
+// lasts until the next header (`[]` returns to the top level), or inside
+// a `prefix { ... }` block, which may be nested or written on one line.
+// Grouped keys are stored under their full dotted names.
+//
+// Comment lines directly preceding an entry become its DocComment. Lines
+// starting with `##` are section headers and always count as comments,
+// never as commented-out entries. A blank line, a `[prefix]` header or a
+// block boundary ends the comment block.
+//
+// Whether any other commented line is a disabled entry or prose depends on
+// the config: in strict mode only `#-` lines are entries, with a schema
+// only declared keys are, and otherwise any line that parses as an entry
+// is one.
+type parser struct {
+	conf      *Config
+	pending   string
+	continued bool
+
+	// line is the number of the last physical line fed, start the number
+	// of the first line of the logical line being parsed.
+	line  int
+	start int
+
+	// emit receives parsed entries; it stores them in conf by default.
+	emit func(e *ConfigEntry)
+	// short and written hold the key of the entry being emitted as written
+	// in the document, without and with its block prefix.
+	short   string
+	written string
+	// onComment, if set, receives every commented line that is not a
+	// section header.
+	onComment func(line string)
+
+	section string
+	blocks  []string
+
+	doc []string
+
+	// sum hashes the physical lines fed, for a checksum trailer. trailer
+	// is set once the trailer is read.
+	sum         hash.Hash
+	trailer     bool
+	badChecksum bool
+}
+
+func newParser(conf *Config) *parser {
+	p := new(parser)
+	p.conf = conf
+	p.emit = conf.setWhileParsing
+	p.sum = sha256.New()
+	conf.diagnostics = nil
+	conf.version = 0
+	conf.versionLine = 0
+	conf.raw = nil
+	return p
+}
+
+func isContinued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+func continuationText(line string, commented bool) string {
+	line = strings.TrimLeft(line, " \t")
+	if commented && len(line) > 0 && line[0] == '#' {
+		line = strings.TrimLeft(line[1:], " \t")
+	}
+	return line
+}
+
+func (p *parser) feed(line string) {
+	p.conf.raw = append(p.conf.raw, line)
+	p.line++
+	if !p.continued {
+		p.start = p.line
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, checksumTrailer) {
+			p.checkTrailer(trimmed)
+			return
+		} else if p.trailer && trimmed != "" {
+			p.trailer = false
+			p.badChecksum = true
+			p.report("", "content after the checksum trailer")
+		}
+	}
+	p.sum.Write([]byte(line + "\n"))
+	if p.continued {
+		commented := strings.HasPrefix(strings.TrimLeft(p.pending, " \t"), "#")
+		line = continuationText(line, commented)
+	}
+
+	trimmed := strings.TrimRight(line, " \t")
+	if isContinued(trimmed) {
+		p.pending += trimmed[:len(trimmed)-1]
+		p.continued = true
+		return
+	}
+
+	p.pending += line
+	p.continued = false
+	p.handle(p.pending)
+	p.pending = ""
+}
+
+// finish flushes a dangling continuation at the end of input.
+func (p *parser) finish() {
+	if p.continued {
+		p.continued = false
+		p.handle(p.pending)
+		p.pending = ""
+	}
+}
+
+func (p *parser) handle(line string) {
+	trimmed := strings.TrimSpace(line)
+
+	if trimmed == "" {
+		p.doc = p.doc[:0]
+		return
+	}
+
+	if version, ok, err := parseVersionHeader(trimmed); ok {
+		p.doc = p.doc[:0]
+		if err != nil {
+			p.report("", "%v", err)
+			return
+		}
+		p.conf.version = version
+		p.conf.versionLine = p.start
+		return
+	}
+
+	if isSectionHeader(trimmed) {
+		p.doc = append(p.doc, commentText(trimmed))
+		return
+	}
+
+	if len(trimmed) > 1 && trimmed[0] == '[' && trimmed[len(trimmed)-1] == ']' {
+		p.doc = p.doc[:0]
+		p.section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+		return
+	}
+
+	if trimmed == "}" {
+		p.doc = p.doc[:0]
+		if len(p.blocks) > 0 {
+			p.blocks = p.blocks[:len(p.blocks)-1]
+		}
+		return
+	}
+
+	if name, rest, ok := blockOpening(trimmed); ok {
+		p.doc = p.doc[:0]
+		p.blocks = append(p.blocks, name)
+		rest = strings.TrimSpace(rest)
+		if strings.HasSuffix(rest, "}") {
+			p.handle(rest[:len(rest)-1])
+			p.blocks = p.blocks[:len(p.blocks)-1]
+		} else if rest != "" {
+			p.handle(rest)
+		}
+		return
+	}
+
+	var short, written string
+	e := p.conf.parseLine(trimmed, func(name string) string {
+		short, written = name, p.qualify(name)
+		return written
+	})
+	if e == nil {
+		if trimmed[0] == '#' {
+			p.comment(trimmed)
+		}
+		return
+	}
+	if e.name != written {
+		p.report(written, "deprecated key, use '%s'", e.name)
+	}
+	e.DocComment = p.docComment()
+	e.line = p.start
+	p.short, p.written = short, written
+	p.emit(e)
+}
+
+// report records a diagnostic for the logical line being parsed.
+func (p *parser) report(key string, format string, args ...interface{}) {
+	p.conf.diagnostics = append(p.conf.diagnostics, Diagnostic{
+		Line:    p.start,
+		Key:     key,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (p *parser) comment(line string) {
+	p.doc = append(p.doc, commentText(line))
+	if p.onComment != nil {
+		p.onComment(line)
+	}
+}
+
+// docComment returns the pending comment block without its surrounding
+// empty lines and resets it.
+func (p *parser) docComment() string {
+	doc := p.doc
+	for len(doc) > 0 && doc[0] == "" {
+		doc = doc[1:]
+	}
+	for len(doc) > 0 && doc[len(doc)-1] == "" {
+		doc = doc[:len(doc)-1]
+	}
+	p.doc = p.doc[:0]
+	return strings.Join(doc, "\n")
+}
+
+// parseLine parses a trimmed logical line the way f reads it, returning
+// nil for comments and lines that are not entries. qualify maps the key as
+// written to the stored key.
+func (f *Config) parseLine(line string, qualify func(string) string) *ConfigEntry {
+	if isSectionHeader(line) || strings.HasPrefix(line, directivePrefix) {
+		return nil
+	}
+	disabled := isDisabledMarked(line)
+	if disabled {
+		line = "#" + line[len(disabledMarker):]
+	} else if line != "" && line[0] == '#' && f.strict {
+		return nil
+	}
+
+	e := ParseString(line)
+	if e == nil {
+		return nil
+	}
+	e.name, _ = f.schema.Canonical(qualify(e.name))
+	if !disabled && !e.IsActive && f.schema != nil && f.schema.Lookup(e.name) == nil {
+		return nil
+	}
+	return e
+}
+
+// blockOpening splits `prefix { rest` into the block prefix and the rest
+// of the line.
+func blockOpening(line string) (string, string, bool) {
+	if line == "" || line[0] == '#' {
+		return "", "", false
+	}
+	i := strings.IndexByte(line, '{')
+	if i < 0 {
+		return "", "", false
+	}
+	name := strings.TrimSpace(line[:i])
+	if name == "" || strings.ContainsAny(name, " \t\"#") {
+		return "", "", false
+	}
+	return name, line[i+1:], true
+}
+
+func (p *parser) qualify(name string) string {
+	parts := make([]string, 0, len(p.blocks)+2)
+	if p.section != "" {
+		parts = append(parts, p.section)
+	}
+	parts = append(parts, p.blocks...)
+	return strings.Join(append(parts, name), ".")
+}
+
+// wrapLine splits line into physical lines no longer than width where
+// possible, using backslash continuations. Lines are only broken after a
+// space or a comma, so tokens are never cut. Zero width disables wrapping.
+func wrapLine(line string, width int) []string {
+	if width <= 0 || len(line) <= width {
+		return []string{line}
+	}
+
+	indent := "\t"
+	if strings.HasPrefix(line, "#") {
+		indent = "#\t"
+	}
+
+	res := make([]string, 0, 2)
+	prefix := ""
+	for len(prefix)+len(line) > width {
+		cut := breakPoint(line, width-len(prefix)-1)
+		if cut <= 0 {
+			break
+		}
+		res = append(res, prefix+line[:cut]+"\\")
+		line = line[cut:]
+		prefix = indent
+	}
+	return append(res, prefix+line)
+}
+
+// breakPoint returns the last position not beyond limit right after a
+// space or a comma, or the first such position after limit if there is
+// none before it. It returns 0 if the line cannot be broken.
+func breakPoint(line string, limit int) int {
+	best := 0
+	for i := 1; i < len(line); i++ {
+		if line[i] == ' ' || line[i] == '\t' {
+			continue
+		}
+		if line[i-1] != ' ' && line[i-1] != ',' {
+			continue
+		}
+		if i > limit && best > 0 {
+			break
+		}
+		best = i
+		if i > limit {
+			break
+		}
+	}
+	return best
+}
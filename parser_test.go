@@ -0,0 +1,202 @@
+package ggo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfig_Continuation(t *testing.T) {
+	testData := []string{
+		"filter.rule \"udp port 11211 \\",
+		"    or udp port 53\" # memcached \\",
+		"\tand dns",
+		"prefixes 10.0.0.0/8,\\",
+		"\t192.168.0.0/16,\\",
+		"\t172.16.0.0/12",
+		"#disabled.list 1.1.1.1/32,\\",
+		"#\t2.2.2.2/32",
+		"tail.key value \\",
+	}
+
+	file := NewConfig()
+	file.FromStrings(testData)
+
+	file.checkEntry(t, true, "filter.rule", "\"udp port 11211 or udp port 53\"", "memcached and dns")
+	file.checkEntry(t, true, "prefixes", "10.0.0.0/8,192.168.0.0/16,172.16.0.0/12", "")
+	file.checkEntry(t, false, "disabled.list", "1.1.1.1/32,2.2.2.2/32", "")
+	file.checkEntry(t, true, "tail.key", "value", "")
+
+	if file.Len() != 0 {
+		t.Errorf("Some fields (%d) left unprocessed %v\n", file.Len(), file.fields)
+	}
+}
+
+func TestConfig_WrapWidth(t *testing.T) {
+	value := "10.0.0.0/8,192.168.0.0/16,172.16.0.0/12,100.64.0.0/10,198.18.0.0/15"
+	testData := []string{
+		"acl.prefixes " + value,
+		"#acl.disabled " + value,
+		"short 1",
+	}
+
+	file := NewConfig()
+	file.SetWrapWidth(32)
+	file.FromStrings(testData)
+
+	str := file.String()
+	for _, line := range strings.Split(str, "\n") {
+		if len(line) > 32 {
+			t.Errorf("line '%s' is longer than wrap width\n", line)
+		}
+	}
+
+	parsed := NewConfig()
+	parsed.FromString(str)
+	parsed.checkEntry(t, true, "acl.prefixes", value, "")
+	parsed.checkEntry(t, false, "acl.disabled", value, "")
+	parsed.checkEntry(t, true, "short", "1", "")
+}
+
+func TestWrapLine_Unbreakable(t *testing.T) {
+	line := "key " + strings.Repeat("x", 40)
+	got := wrapLine(line, 10)
+	if len(got) != 2 || got[0] != "key \\" || got[1] != "\t"+strings.Repeat("x", 40) {
+		t.Errorf("unexpected wrap %q\n", got)
+	}
+}
+
+func TestConfig_Blocks(t *testing.T) {
+	testData := []string{
+		"top 1",
+		"tb.sym.syn {",
+		"\tttl.32.speed 1536",
+		"\t#ttl.32.setting 500",
+		"\tlow {",
+		"\t\t32.speed 640",
+		"\t}",
+		"}",
+		"tb.asym.syn { ttl.24.speed 700 }",
+		"[sflow.drop]",
+		"rate 0 # 1000",
+		"speed 40",
+		"[]",
+		"pcap-speed 220",
+	}
+
+	file := NewConfig()
+	file.FromStrings(testData)
+
+	file.checkEntry(t, true, "top", "1", "")
+	file.checkEntry(t, true, "tb.sym.syn.ttl.32.speed", "1536", "")
+	file.checkEntry(t, false, "tb.sym.syn.ttl.32.setting", "500", "")
+	file.checkEntry(t, true, "tb.sym.syn.low.32.speed", "640", "")
+	file.checkEntry(t, true, "tb.asym.syn.ttl.24.speed", "700", "")
+	file.checkEntry(t, true, "sflow.drop.rate", "0", "1000")
+	file.checkEntry(t, true, "sflow.drop.speed", "40", "")
+	file.checkEntry(t, true, "pcap-speed", "220", "")
+
+	if file.Len() != 0 {
+		t.Errorf("Some fields (%d) left unprocessed %v\n", file.Len(), file.fields)
+	}
+}
+
+func TestConfig_Layout(t *testing.T) {
+	testData := []string{
+		"pcap-speed 220",
+		"tb.sym.syn.ttl.32.speed 1536",
+		"#tb.sym.syn.ttl.32.setting 500",
+		"tb.sym.syn.low.32.speed 640",
+		"tb.asym.syn.ttl.24.speed 700",
+	}
+
+	for _, layout := range []Layout{LayoutSections, LayoutBlocks} {
+		file := NewConfig()
+		file.FromStrings(testData)
+		file.SetLayout(layout, 3)
+		str := file.String()
+
+		expected := "pcap-speed 220\ntb.asym.syn.ttl.24.speed 700\n\n"
+		if layout == LayoutSections {
+			expected += "[tb.sym.syn]\nlow.32.speed 640\n# ttl.32.setting 500\nttl.32.speed 1536"
+		} else {
+			expected += "tb.sym.syn {\n\tlow.32.speed 640\n\t# ttl.32.setting 500\n\tttl.32.speed 1536\n}"
+		}
+		if str != expected {
+			t.Errorf("layout %d:\n%s\nexpected:\n%s\n", layout, str, expected)
+		}
+
+		parsed := NewConfig()
+		parsed.FromString(str)
+		for _, line := range testData {
+			e := ParseString(line)
+			parsed.checkEntry(t, e.IsActive, e.Name(), e.Value, e.Comment)
+		}
+	}
+}
+
+func TestConfig_DocComment(t *testing.T) {
+	testData := []string{
+		"## Sync",
+		"#",
+		"# multicast groups are listed below",
+		"sync 239.0.0.3",
+		"",
+		"# a stale comment",
+		"",
+		"pcap-speed 220 # pps",
+		"#switch off cookie filter",
+		"#cores-per-port 8",
+	}
+
+	file := NewConfig()
+	file.SetKeyMultiple("sync", true)
+	file.FromStrings(testData)
+
+	sync := file.Get("sync").(*ConfigMultiEntry).Get("239.0.0.3")
+	if sync.DocComment != "Sync\n\nmulticast groups are listed below" {
+		t.Errorf("invalid doc comment '%s'\n", sync.DocComment)
+	}
+	if e := file.Get("pcap-speed").(*ConfigEntry); e.DocComment != "" || e.Comment != "pps" {
+		t.Errorf("invalid pcap-speed entry %v\n", e)
+	}
+	if e := file.Get("cores-per-port").(*ConfigEntry); e.DocComment != "switch off cookie filter" {
+		t.Errorf("invalid doc comment '%s'\n", e.DocComment)
+	}
+
+	parsed := file.CopyScheme()
+	parsed.FromString(file.String())
+	if parsed.String() != file.String() {
+		t.Errorf("doc comments not preserved:\n%s\n---\n%s\n", file.String(), parsed.String())
+	}
+	if e := parsed.Get("cores-per-port").(*ConfigEntry); e.DocComment != "switch off cookie filter" {
+		t.Errorf("invalid doc comment after write '%s'\n", e.DocComment)
+	}
+
+	update := file.CopyScheme()
+	update.FromStrings([]string{"cores-per-port 4", "sync 239.0.0.3"})
+	merged := Merge(file, update)
+	if e := merged.Get("cores-per-port").(*ConfigEntry); e.DocComment != "switch off cookie filter" || !e.IsActive {
+		t.Errorf("doc comment lost in merge %v\n", e)
+	}
+	if e := merged.Get("sync").(*ConfigMultiEntry).Get("239.0.0.3"); e.DocComment == "" {
+		t.Errorf("doc comment lost in multi-entry merge %v\n", e)
+	}
+	if e := update.Get("cores-per-port").(*ConfigEntry); e.DocComment != "" {
+		t.Errorf("merge modified its argument %v\n", e)
+	}
+}
+
+func TestConfig_ParseBytes(t *testing.T) {
+	long := strings.Repeat("x", 70000)
+	doc := "filter " + long + "\r\nvlan 10\r\n"
+
+	file := NewConfig()
+	if err := file.ParseConfig([]byte(doc)); err != nil {
+		t.Fatalf("ParseConfig failed: %v\n", err)
+	}
+	file.checkEntry(t, true, "filter", long, "")
+	file.checkEntry(t, true, "vlan", "10", "")
+	if file.Len() != 0 {
+		t.Errorf("Some fields (%d) left unprocessed %v\n", file.Len(), file.fields)
+	}
+}
@@ -0,0 +1,291 @@
+package ggo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// docEntry is an entry of a document being patched, with the physical
+// lines it spans.
+type docEntry struct {
+	entry *ConfigEntry
+	// scope is the section and block prefix the key is written under.
+	scope       string
+	first, last int
+	indent      string
+	// shared is set when the line also holds a block boundary, so that it
+	// cannot be rewritten on its own.
+	shared bool
+}
+
+// PatchDocument rewrites doc, read the way c reads documents, so that it
+// holds the entries of c. Only the lines of keys whose entries differ are
+// rewritten: other lines, comments and the grouping of the document are
+// kept. Changed values replace the line of the value they supersede,
+// values and keys that are gone are removed with their lines, and new
+// keys are added to the `[prefix]` section they belong to, or before the
+// first section. The version header and a checksum trailer are updated.
+//
+// Entries that share a line with a block boundary cannot be rewritten in
+// place; PatchDocument returns an error instead of reformatting them.
+func PatchDocument(doc []byte, c *Config) ([]byte, error) {
+	text := string(doc)
+	newline := "\n"
+	if strings.Contains(text, "\r\n") {
+		newline = "\r\n"
+	}
+	var lines []string
+	if text != "" {
+		lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+
+	orig := c.CopyScheme()
+	p := newParser(orig)
+	var entries []*docEntry
+	p.emit = func(e *ConfigEntry) {
+		d := &docEntry{
+			entry: e.Copy().(*ConfigEntry),
+			scope: strings.TrimSuffix(strings.TrimSuffix(p.written, p.short), "."),
+			first: p.start - 1,
+			last:  p.line - 1,
+		}
+		line := lines[d.first]
+		d.indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		_, _, d.shared = blockOpening(strings.TrimSpace(line))
+		entries = append(entries, d)
+		orig.setWhileParsing(e)
+	}
+	for _, line := range lines {
+		p.feed(strings.TrimSuffix(line, "\r"))
+	}
+	p.finish()
+
+	byKey := make(map[string][]*docEntry)
+	for _, d := range entries {
+		byKey[d.entry.Name()] = append(byKey[d.entry.Name()], d)
+	}
+
+	// replace holds the lines written in place of the entry starting at a
+	// line, drop the lines left out and after the lines added after a
+	// line, where -1 is the start of the document.
+	replace := make(map[int][]string)
+	drop := make(map[int]bool)
+	after := make(map[int][]string)
+	remove := func(d *docEntry) {
+		for i := d.first; i <= d.last; i++ {
+			drop[i] = true
+		}
+	}
+	rewrite := func(d *docEntry, lines []string) {
+		remove(d)
+		replace[d.first] = lines
+	}
+
+	var added []string
+	for _, k := range unionKeys(orig.fields, c.fields) {
+		if renderField(orig.fields[k]) == renderField(c.fields[k]) {
+			continue
+		}
+		found := byKey[k]
+		if len(found) == 0 {
+			added = append(added, k)
+			continue
+		}
+		for _, d := range found {
+			if d.shared {
+				return nil, fmt.Errorf("line %d: cannot rewrite %s in place, it shares its line with a block", d.first+1, k)
+			}
+		}
+		last := found[len(found)-1]
+		short, ok := scopedName(k, last.scope)
+		if !ok {
+			return nil, fmt.Errorf("line %d: cannot write %s under %s", last.first+1, k, last.scope)
+		}
+
+		switch v := c.fields[k].(type) {
+		case nil:
+			for _, d := range found {
+				remove(d)
+			}
+		case *ConfigEntry:
+			// Rewrite the line the stored entry was read from; other
+			// lines of the key lost to it when reading and still do.
+			stored := found[len(found)-1]
+			for _, d := range found {
+				if d.entry.String() == renderField(orig.fields[k]) {
+					stored = d
+				}
+			}
+			rewrite(stored, c.entryLines(withoutDoc(v), short, stored.indent))
+		case *ConfigMultiEntry:
+			written := make(map[string]bool)
+			for _, d := range found {
+				e, exists := v.Entries[d.entry.Value]
+				switch {
+				case !exists:
+					remove(d)
+				case e.String() == d.entry.String():
+					written[e.Value] = true
+				case written[e.Value]:
+					remove(d)
+				default:
+					written[e.Value] = true
+					rewrite(d, c.entryLines(withoutDoc(e), short, d.indent))
+				}
+			}
+			for _, value := range v.sortedValues() {
+				if !written[value] {
+					after[last.last] = append(after[last.last], c.entryLines(v.Entries[value], short, last.indent)...)
+				}
+			}
+		}
+	}
+
+	if len(added) > 0 {
+		sections, top := documentSections(lines)
+		for _, k := range added {
+			at, short, scope := top, k, ""
+			for _, s := range sections {
+				if strings.HasPrefix(k, s.name+".") && len(s.name) > len(scope) {
+					at, short, scope = s.last, k[len(s.name)+1:], s.name
+				}
+			}
+			after[at] = append(after[at], c.entryLines(c.fields[k], short, "")...)
+		}
+		if top < 0 && len(sections) > 0 && len(after[-1]) > 0 {
+			after[-1] = append(after[-1], "")
+		}
+	}
+
+	if c.version != orig.version {
+		header := versionHeader + " " + strconv.Itoa(c.version)
+		switch {
+		case orig.versionLine > 0 && c.version > 0:
+			replace[orig.versionLine-1] = []string{header}
+			drop[orig.versionLine-1] = true
+		case orig.versionLine > 0:
+			drop[orig.versionLine-1] = true
+		default:
+			after[-1] = append([]string{header}, after[-1]...)
+		}
+	}
+
+	res := make([]string, 0, len(lines))
+	add := func(lines []string) {
+		for _, line := range lines {
+			res = append(res, line+strings.TrimSuffix(newline, "\n"))
+		}
+	}
+	add(after[-1])
+	for i, line := range lines {
+		add(replace[i])
+		if !drop[i] {
+			res = append(res, line)
+		}
+		add(after[i])
+	}
+
+	out := ""
+	if len(res) > 0 {
+		out = strings.Join(res, "\n") + "\n"
+	}
+	patched := reseal([]byte(out))
+
+	check := c.CopyScheme()
+	if err := check.parseData(patched); err != nil && err != ErrChecksum {
+		return nil, err
+	}
+	for _, k := range unionKeys(check.fields, c.fields) {
+		if renderField(check.fields[k]) != renderField(c.fields[k]) {
+			return nil, fmt.Errorf("cannot rewrite %s in place", k)
+		}
+	}
+	return patched, nil
+}
+
+// withoutDoc returns e without its doc comment, for rewriting an entry
+// below the comment it already has.
+func withoutDoc(e *ConfigEntry) *ConfigEntry {
+	res := e.Copy().(*ConfigEntry)
+	res.DocComment = ""
+	return res
+}
+
+// scopedName returns key as written under scope.
+func scopedName(key string, scope string) (string, bool) {
+	if scope == "" {
+		return key, true
+	}
+	if !strings.HasPrefix(key, scope+".") {
+		return "", false
+	}
+	return key[len(scope)+1:], true
+}
+
+// isComment reports whether a line is a comment, as opposed to a
+// directive such as the version header.
+func isComment(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "#") && !strings.HasPrefix(line, directivePrefix)
+}
+
+// docSection is a `[prefix]` section of a document, with the last line
+// holding one of its entries, or its header.
+type docSection struct {
+	name string
+	last int
+}
+
+// documentSections returns the sections of a document and the line new
+// top-level keys are added after: the last one of the content preceding
+// the first section and the comments attached to it, or -1.
+func documentSections(lines []string) ([]docSection, int) {
+	var sections []docSection
+	top := len(lines) - 1
+	current := -1
+	continued := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		wasContinued := continued
+		continued = isContinued(strings.TrimRight(strings.TrimSuffix(line, "\r"), " \t"))
+		if wasContinued {
+			if current >= 0 {
+				sections[current].last = i
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, checksumTrailer) {
+			continue
+		}
+		if len(trimmed) > 1 && trimmed[0] == '[' && trimmed[len(trimmed)-1] == ']' {
+			name := strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if len(sections) == 0 {
+				top = i - 1
+			}
+			current = -1
+			if name != "" {
+				sections = append(sections, docSection{name: name, last: i})
+				current = len(sections) - 1
+			}
+			continue
+		}
+		if current >= 0 && trimmed != "" && trimmed[0] != '#' {
+			sections[current].last = i
+		}
+	}
+
+	if len(sections) == 0 {
+		for top >= 0 && (strings.TrimSpace(lines[top]) == "" || strings.HasPrefix(strings.TrimSpace(lines[top]), checksumTrailer)) {
+			top--
+		}
+		return nil, top
+	}
+	for top >= 0 && isComment(lines[top]) {
+		top--
+	}
+	for top >= 0 && strings.TrimSpace(lines[top]) == "" {
+		top--
+	}
+	return sections, top
+}
@@ -0,0 +1,96 @@
+package ggo
+
+import "testing"
+
+func TestPatchDocument(t *testing.T) {
+	doc := "# switch\n" +
+		"vlan\t10   # access\n" +
+		"port 161\n" +
+		"\n" +
+		"# tagged bridge\n" +
+		"[tb.sym]\n" +
+		"# mtu in bytes\n" +
+		"mtu 1500\n" +
+		"speed 1G\n" +
+		"vrf red\n"
+
+	file := NewConfig()
+	file.SetKeyMultiple("tb.sym.vrf", true)
+	file.ParseConfig([]byte(doc))
+	file.Set(NewEntry("vlan", "20"))
+	file.Delete("tb.sym.speed")
+	file.Add(NewEntry("tb.sym.vrf", "blue"))
+	file.Set(NewEntry("tb.sym.rate", "2"))
+	file.Set(NewEntry("mode", "trunk"))
+
+	expected := "# switch\n" +
+		"vlan 20\n" +
+		"port 161\n" +
+		"mode trunk\n" +
+		"\n" +
+		"# tagged bridge\n" +
+		"[tb.sym]\n" +
+		"# mtu in bytes\n" +
+		"mtu 1500\n" +
+		"vrf red\n" +
+		"vrf blue\n" +
+		"rate 2\n"
+
+	got, err := PatchDocument([]byte(doc), file)
+	if err != nil || string(got) != expected {
+		t.Errorf("unexpected patch (%v):\n%s\n", err, got)
+	}
+}
+
+func TestPatchDocument_Unchanged(t *testing.T) {
+	doc := "#ggo:version 1\r\n" +
+		"a {\r\n" +
+		"\tb 1\r\n" +
+		"}\r\n" +
+		"c 2,\\\r\n" +
+		"\t3\r\n"
+
+	file := NewConfig()
+	file.ParseConfig([]byte(doc))
+	got, err := PatchDocument([]byte(doc), file)
+	if err != nil || string(got) != doc {
+		t.Errorf("unchanged document rewritten (%v):\n%q\n", err, got)
+	}
+
+	file.Set(NewEntry("a.b", "4"))
+	file.Set(NewEntry("c", "5"))
+	got, err = PatchDocument([]byte(doc), file)
+	if err != nil || string(got) != "#ggo:version 1\r\na {\r\n\tb 4\r\n}\r\nc 5\r\n" {
+		t.Errorf("unexpected patch (%v):\n%q\n", err, got)
+	}
+}
+
+func TestPatchDocument_Shared(t *testing.T) {
+	doc := "a { b 1 }\n"
+
+	file := NewConfig()
+	file.ParseConfig([]byte(doc))
+	file.Set(NewEntry("a.b", "2"))
+	if _, err := PatchDocument([]byte(doc), file); err == nil {
+		t.Errorf("PatchDocument rewrote an entry sharing its line with a block\n")
+	}
+}
+
+func TestPatchDocument_Checksum(t *testing.T) {
+	file := NewConfig()
+	file.SetChecksum(true)
+	file.Set(NewEntry("a", "1"))
+	doc := file.fileContent()
+
+	file.Set(NewEntry("b", "2"))
+	got, err := PatchDocument(doc, file)
+	if err != nil {
+		t.Fatalf("PatchDocument failed: %v\n", err)
+	}
+	if string(got) != string(file.fileContent()) {
+		t.Errorf("unexpected patch:\n%s\n", got)
+	}
+	if err := NewConfig().ParseConfig(got); err != nil {
+		t.Errorf("patched document does not match its checksum: %v\n", err)
+	}
+}
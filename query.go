@@ -0,0 +1,400 @@
+package ggo
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Query selects entries of a config. Queries are written as conditions
+// combined with `and`, `or`, `not` and parentheses; conditions written
+// one after the other must all hold. The conditions are:
+//
+//	tb.*.speed          the key matches a pattern, as in a schema
+//	key = pattern       the same; != negates it
+//	active, inactive    the entry is, or is not, commented out
+//	value OP operand    OP is one of = != < <= > >= ~ in
+//	comment ~ regexp    the inline or doc comment matches
+//
+// Values are compared with the schema type of their key when it parses
+// both sides, or else as integers, rates with k/M/G suffixes, IP addresses
+// or durations. A value that is not of the type of the operand is only
+// different from it. Operands of none of these types compare as strings.
+// `value in 198.18.1.0/24` holds for addresses and prefixes inside the
+// given prefix. `~` matches regular expressions. Operands containing
+// spaces or operator characters are written in double quotes.
+//
+// For example `tb.*.syn.*.speed active value > 1000` or
+// `sync-neighbour value in 198.18.1.0/24`.
+type Query struct {
+	expr string
+	cond condition
+}
+
+// condition reports whether an entry stored under name in c is selected.
+type condition func(c *Config, name string, e *ConfigEntry) bool
+
+// ParseQuery parses a query expression.
+func ParseQuery(expr string) (*Query, error) {
+	tokens, err := tokenizeQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s'", p.tokens[p.pos].text)
+	}
+	return &Query{expr: expr, cond: cond}, nil
+}
+
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Select returns the entries of c the query selects, in key and value
+// order.
+func (q *Query) Select(c *Config) []*ConfigEntry {
+	var res []*ConfigEntry
+	for name, e := range c.All() {
+		for _, entry := range entriesOf(e) {
+			if q.cond(c, name, entry) {
+				res = append(res, entry)
+			}
+		}
+	}
+	return res
+}
+
+// Select returns the entries selected by the query expr; see Query.
+func (f *Config) Select(expr string) ([]*ConfigEntry, error) {
+	q, err := ParseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Select(f), nil
+}
+
+type queryToken struct {
+	text string
+	// quoted tokens are operands, never keywords or operators.
+	quoted bool
+}
+
+const queryOperatorChars = "=!<>~"
+
+func tokenizeQuery(expr string) ([]queryToken, error) {
+	var res []queryToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			res = append(res, queryToken{text: string(c)})
+			i++
+		case c == '"':
+			end := strings.IndexByte(expr[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			res = append(res, queryToken{text: expr[i+1 : i+1+end], quoted: true})
+			i += end + 2
+		case strings.IndexByte(queryOperatorChars, c) >= 0:
+			j := i + 1
+			for j < len(expr) && strings.IndexByte(queryOperatorChars, expr[j]) >= 0 {
+				j++
+			}
+			res = append(res, queryToken{text: expr[i:j]})
+			i = j
+		default:
+			j := i
+			for j < len(expr) && !strings.ContainsRune(" \t()\""+queryOperatorChars, rune(expr[j])) {
+				j++
+			}
+			res = append(res, queryToken{text: expr[i:j]})
+			i = j
+		}
+	}
+	return res, nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+// peek reports whether the next token is the unquoted word or operator
+// text, or for an empty text whether there is a next token.
+func (p *queryParser) peek(text string) bool {
+	if p.pos >= len(p.tokens) {
+		return false
+	}
+	t := p.tokens[p.pos]
+	return text == "" || !t.quoted && t.text == text
+}
+
+func (p *queryParser) next() (queryToken, error) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, fmt.Errorf("unexpected end of query")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *queryParser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(c *Config, name string, e *ConfigEntry) bool {
+			return l(c, name, e) || right(c, name, e)
+		}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("") && !p.peek("or") && !p.peek(")") {
+		if p.peek("and") {
+			p.pos++
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(c *Config, name string, e *ConfigEntry) bool {
+			return l(c, name, e) && right(c, name, e)
+		}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseNot() (condition, error) {
+	if p.peek("not") {
+		p.pos++
+		cond, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(c *Config, name string, e *ConfigEntry) bool {
+			return !cond(c, name, e)
+		}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (condition, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.quoted {
+		return keyCondition("=", t.text), nil
+	}
+
+	switch t.text {
+	case "(":
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+		return cond, nil
+	case "active", "inactive":
+		active := t.text == "active"
+		return func(c *Config, name string, e *ConfigEntry) bool {
+			return e.IsActive == active
+		}, nil
+	case "key", "value", "comment":
+		op, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		operand, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch t.text {
+		case "key":
+			if op.text != "=" && op.text != "!=" {
+				return nil, fmt.Errorf("invalid key operator '%s'", op.text)
+			}
+			return keyCondition(op.text, operand.text), nil
+		case "value":
+			return valueCondition(op.text, operand.text)
+		default:
+			return commentCondition(op.text, operand.text)
+		}
+	case ")", "and", "or", "not":
+		return nil, fmt.Errorf("unexpected '%s'", t.text)
+	}
+	if strings.ContainsAny(t.text, queryOperatorChars) {
+		return nil, fmt.Errorf("unexpected '%s'", t.text)
+	}
+	return keyCondition("=", t.text), nil
+}
+
+func keyCondition(op string, pattern string) condition {
+	return func(c *Config, name string, e *ConfigEntry) bool {
+		match := name == pattern || isKeyPattern(pattern) && matchKey(pattern, name)
+		return match == (op == "=")
+	}
+}
+
+func commentCondition(op string, operand string) (condition, error) {
+	if op != "~" {
+		return nil, fmt.Errorf("invalid comment operator '%s'", op)
+	}
+	re, err := regexp.Compile(operand)
+	if err != nil {
+		return nil, err
+	}
+	return func(c *Config, name string, e *ConfigEntry) bool {
+		return re.MatchString(e.Comment) || re.MatchString(e.DocComment)
+	}, nil
+}
+
+func valueCondition(op string, operand string) (condition, error) {
+	switch op {
+	case "~":
+		re, err := regexp.Compile(operand)
+		if err != nil {
+			return nil, err
+		}
+		return func(c *Config, name string, e *ConfigEntry) bool {
+			return re.MatchString(unquote(e.Value))
+		}, nil
+	case "in":
+		prefix, err := netip.ParsePrefix(operand)
+		if err != nil {
+			return nil, err
+		}
+		prefix = prefix.Masked()
+		return func(c *Config, name string, e *ConfigEntry) bool {
+			return prefixContains(prefix, unquote(e.Value))
+		}, nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return func(c *Config, name string, e *ConfigEntry) bool {
+			var t Type
+			if spec := c.schema.Lookup(name); spec != nil {
+				t = spec.Type
+			}
+			cmp, ok := compareValues(t, unquote(e.Value), operand)
+			if !ok {
+				return op == "!="
+			}
+			switch op {
+			case "=":
+				return cmp == 0
+			case "!=":
+				return cmp != 0
+			case "<":
+				return cmp < 0
+			case "<=":
+				return cmp <= 0
+			case ">":
+				return cmp > 0
+			}
+			return cmp >= 0
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid value operator '%s'", op)
+}
+
+// prefixContains reports whether value, an address or a prefix, lies in
+// prefix.
+func prefixContains(prefix netip.Prefix, value string) bool {
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return prefix.Contains(addr)
+	}
+	if p, err := netip.ParsePrefix(value); err == nil {
+		return p.Bits() >= prefix.Bits() && prefix.Contains(p.Addr())
+	}
+	return false
+}
+
+// queryTypes are tried in order to compare values of keys without a
+// schema type.
+var queryTypes = []Type{TypeInt, TypeRate, TypeIP, TypeDuration}
+
+// compareValues compares a and b as values of type t, returning -1, 0 or
+// 1. Values of types without an order, such as MAC addresses, are ordered
+// by their canonical form. ok is false when b is typed, such as a number,
+// but a is not a value of its type.
+func compareValues(t Type, a string, b string) (cmp int, ok bool) {
+	types := queryTypes
+	if t != nil {
+		types = append([]Type{t}, types...)
+	}
+	typed := false
+	for _, t := range types {
+		pb, err := t.Parse(b)
+		if err != nil {
+			continue
+		}
+		typed = true
+		pa, err := t.Parse(a)
+		if err != nil {
+			continue
+		}
+		if cmp, ok := compareParsed(pa, pb); ok {
+			return cmp, true
+		}
+		return strings.Compare(t.Format(pa), t.Format(pb)), true
+	}
+	if typed {
+		return 0, false
+	}
+	return strings.Compare(a, b), true
+}
+
+func compareParsed(a interface{}, b interface{}) (int, bool) {
+	switch va := a.(type) {
+	case int64:
+		vb := b.(int64)
+		return compareOrdered(va < vb, va > vb), true
+	case uint64:
+		vb := b.(uint64)
+		return compareOrdered(va < vb, va > vb), true
+	case uint16:
+		vb := b.(uint16)
+		return compareOrdered(va < vb, va > vb), true
+	case time.Duration:
+		vb := b.(time.Duration)
+		return compareOrdered(va < vb, va > vb), true
+	case netip.Addr:
+		return va.Compare(b.(netip.Addr)), true
+	}
+	return 0, false
+}
+
+func compareOrdered(less bool, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package ggo
+
+import (
+	"strings"
+	"testing"
+)
+
+func selectNames(t *testing.T, c *Config, expr string) []string {
+	entries, err := c.Select(expr)
+	if err != nil {
+		t.Errorf("%s: %v\n", expr, err)
+		return nil
+	}
+	res := make([]string, len(entries))
+	for i, e := range entries {
+		res[i] = e.Name() + " " + e.Value
+	}
+	return res
+}
+
+func Test_Select(t *testing.T) {
+	c := NewConfig()
+	c.SetKeyMultiple("sync-neighbour", true)
+	c.SetSchema(NewSchema(
+		&KeySpec{Name: "tb.*.syn.*.speed", Type: TypeRate},
+		&KeySpec{Name: "sync-neighbour", Multiple: true},
+		&KeySpec{Name: "vlan"},
+	))
+	c.FromString(`tb.a.syn.in.speed 2k
+tb.b.syn.in.speed 900
+# tb.c.syn.in.speed 5000
+tb.d.ack.in.speed 9000
+sync-neighbour 198.18.1.7
+sync-neighbour "198.18.2.1"
+sync-neighbour 198.18.1.0/25 # local
+# pending removal
+vlan 12`)
+
+	cases := []struct {
+		expr     string
+		expected []string
+	}{
+		{"tb.*.syn.*.speed active value > 1000", []string{"tb.a.syn.in.speed 2k"}},
+		{"tb.*.syn.*.speed value >= 900", []string{"tb.a.syn.in.speed 2k", "tb.b.syn.in.speed 900", "tb.c.syn.in.speed 5000"}},
+		{"tb.*.syn.*.speed inactive", []string{"tb.c.syn.in.speed 5000"}},
+		{"sync-neighbour value in 198.18.1.0/24", []string{"sync-neighbour 198.18.1.0/25", "sync-neighbour 198.18.1.7"}},
+		{"sync-neighbour not value in 198.18.1.0/24", []string{`sync-neighbour "198.18.2.1"`}},
+		{"value = 198.18.2.1", []string{`sync-neighbour "198.18.2.1"`}},
+		{"comment ~ local or comment ~ ^pending", []string{"sync-neighbour 198.18.1.0/25", "vlan 12"}},
+		{"vlan or (key = tb.*.*.*.* and value < 1000)", []string{"tb.b.syn.in.speed 900", "vlan 12"}},
+		{`value ~ "^9"`, []string{"tb.b.syn.in.speed 900", "tb.d.ack.in.speed 9000"}},
+		{"key != tb.*.*.*.speed and value > 10", []string{"vlan 12"}},
+	}
+	for _, tc := range cases {
+		got := selectNames(t, c, tc.expr)
+		if len(got) != len(tc.expected) {
+			t.Errorf("%s: got %v, expected %v\n", tc.expr, got, tc.expected)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.expected[i] {
+				t.Errorf("%s: got %v, expected %v\n", tc.expr, got, tc.expected)
+				break
+			}
+		}
+	}
+
+	for _, expr := range []string{"value >", "(vlan", "vlan )", "value in nowhere", "comment = x", "value ~ (", `"unterminated`, "and vlan"} {
+		if _, err := ParseQuery(expr); err == nil {
+			t.Errorf("%s: accepted\n", expr)
+		}
+	}
+}
+
+func Test_SelectDuration(t *testing.T) {
+	c := NewConfig()
+	c.SetSchema(NewSchema(&KeySpec{Name: "hold", Type: TypeDuration}))
+	c.FromString("timeout 1m\nretry 10s\nhold 90s\nvlan 12")
+
+	for _, tc := range []struct {
+		expr     string
+		expected string
+	}{
+		{"value > 30s", "hold 90s|timeout 1m"},
+		{"value <= 1m", "retry 10s|timeout 1m"},
+		{"hold value = 1m30s", "hold 90s"},
+	} {
+		got := strings.Join(selectNames(t, c, tc.expr), "|")
+		if got != tc.expected {
+			t.Errorf("'%s' selected '%s'\n", tc.expr, got)
+		}
+	}
+}
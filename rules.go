@@ -0,0 +1,228 @@
+package ggo
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Violation is a broken schema constraint. Keys lists every key involved
+// and Lines the source line of each, 0 where unknown.
+type Violation struct {
+	Rule    string
+	Keys    []string
+	Lines   []int
+	Message string
+}
+
+func (v Violation) Error() string {
+	where := make([]string, len(v.Keys))
+	for i, k := range v.Keys {
+		where[i] = k
+		if i < len(v.Lines) && v.Lines[i] > 0 {
+			where[i] = fmt.Sprintf("%s (line %d)", k, v.Lines[i])
+		}
+	}
+	res := v.Message + ": " + strings.Join(where, ", ")
+	if v.Rule != "" {
+		res = v.Rule + ": " + res
+	}
+	return res
+}
+
+// ValidationError lists every violation found by Validate.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Rule is a constraint spanning several keys. Check returns the
+// violations found in c; entries passed to Involve give their key and
+// source line.
+type Rule struct {
+	Name  string
+	Check func(c *Config) []Violation
+}
+
+// AddRule adds cross-key rules checked by Config.Validate.
+func (s *Schema) AddRule(rules ...Rule) {
+	s.rules = append(s.rules, rules...)
+}
+
+// Validate checks the config against the types and rules of its schema.
+// It returns a *ValidationError listing every violation, or nil.
+func (f *Config) Validate() error {
+	var violations []Violation
+	if f.schema != nil {
+		violations = append(violations, f.checkTypes()...)
+		for _, r := range f.schema.rules {
+			for _, v := range r.Check(f) {
+				if v.Rule == "" {
+					v.Rule = r.Name
+				}
+				violations = append(violations, v)
+			}
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+// Involve builds a violation naming the given entries.
+func Involve(message string, entries ...*ConfigEntry) Violation {
+	v := Violation{Message: message}
+	for _, e := range entries {
+		v.Keys = append(v.Keys, e.Name())
+		v.Lines = append(v.Lines, e.Line())
+	}
+	return v
+}
+
+// ActiveEntries returns the active entries of every key matching pattern,
+// in key and value order.
+func (f *Config) ActiveEntries(pattern string) []*ConfigEntry {
+	var res []*ConfigEntry
+	for _, k := range f.sortedKeys() {
+		if k != pattern && !(isKeyPattern(pattern) && matchKey(pattern, k)) {
+			continue
+		}
+		for _, e := range entriesOf(f.fields[k]) {
+			if e.IsActive {
+				res = append(res, e)
+			}
+		}
+	}
+	return res
+}
+
+// Requires demands that every key in required is set whenever a key
+// matching key is set.
+func Requires(key string, required ...string) Rule {
+	return Rule{
+		Name: "requires",
+		Check: func(c *Config) []Violation {
+			var res []Violation
+			for _, e := range c.ActiveEntries(key) {
+				for _, r := range required {
+					if len(c.ActiveEntries(r)) == 0 {
+						v := Involve(fmt.Sprintf("%s is set but %s is not", e.Name(), r), e)
+						v.Keys = append(v.Keys, r)
+						res = append(res, v)
+					}
+				}
+			}
+			return res
+		},
+	}
+}
+
+// Conflicts demands that at most one of keys is set. The values of a
+// single multi-valued key do not conflict with each other.
+func Conflicts(keys ...string) Rule {
+	return Rule{
+		Name: "conflicts",
+		Check: func(c *Config) []Violation {
+			var set []*ConfigEntry
+			names := make(map[string]bool)
+			for _, k := range keys {
+				for _, e := range c.ActiveEntries(k) {
+					if !containsEntry(set, e) {
+						set = append(set, e)
+					}
+					names[e.Name()] = true
+				}
+			}
+			if len(names) > 1 {
+				return []Violation{Involve("keys may not be set together", set...)}
+			}
+			return nil
+		},
+	}
+}
+
+func containsEntry(entries []*ConfigEntry, e *ConfigEntry) bool {
+	for _, v := range entries {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
+
+// WithinPrefix demands that every address set under key lies inside one
+// of the prefixes set under prefixKeys.
+func WithinPrefix(key string, prefixKeys ...string) Rule {
+	return Rule{
+		Name: "within-prefix",
+		Check: func(c *Config) []Violation {
+			var prefixes []*ConfigEntry
+			for _, k := range prefixKeys {
+				prefixes = append(prefixes, c.ActiveEntries(k)...)
+			}
+
+			var res []Violation
+			for _, e := range c.ActiveEntries(key) {
+				addr, err := netip.ParseAddr(unquote(e.Value))
+				if err != nil {
+					res = append(res, Involve("'"+e.Value+"' is not an IP address", e))
+					continue
+				}
+				inside := false
+				for _, p := range prefixes {
+					if prefix, err := netip.ParsePrefix(unquote(p.Value)); err == nil && prefix.Masked().Contains(addr) {
+						inside = true
+						break
+					}
+				}
+				if !inside {
+					msg := fmt.Sprintf("%s is outside of %s", e.Value, strings.Join(prefixKeys, ", "))
+					res = append(res, Involve(msg, append([]*ConfigEntry{e}, prefixes...)...))
+				}
+			}
+			return res
+		},
+	}
+}
+
+// UniqueAcross demands that no value is set more than once across keys.
+func UniqueAcross(keys ...string) Rule {
+	return Rule{
+		Name: "unique-across",
+		Check: func(c *Config) []Violation {
+			seen := make(map[string]*ConfigEntry)
+			var res []Violation
+			for _, k := range keys {
+				for _, e := range c.ActiveEntries(k) {
+					value := unquote(e.Value)
+					if prev, exists := seen[value]; exists {
+						res = append(res, Involve("value '"+value+"' is used more than once", prev, e))
+						continue
+					}
+					seen[value] = e
+				}
+			}
+			return res
+		},
+	}
+}
+
+func unquote(value string) string {
+	if len(value) > 1 && value[0] == '"' && value[len(value)-1] == '"' {
+		return quoteEscapes.Replace(value[1 : len(value)-1])
+	}
+	return value
+}
+
+// quoteEscapes undoes the escaping of quoted values: `\"` for a quote and
+// `\\` for a backslash.
+var quoteEscapes = strings.NewReplacer(`\"`, `"`, `\\`, `\`)
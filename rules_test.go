@@ -0,0 +1,117 @@
+package ggo
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	testData := []string{
+		"sym.prot.ipv4		198.18.1.2/24",
+		"sym.raw.ipv4		198.18.1.128/25",
+		"sync-neighbour 198.18.1.3",
+		"sync-neighbour 10.0.0.1",
+		"sflow.drop.rate		10",
+		"sflow.drop.speed		0",
+		"sflow.raw.rate		0",
+		"sflow.raw.speed		250",
+		"service.ipv4 198.18.5.2/29",
+		"mac		\"ec:93:ed:01:00:00\"",
+		"nh.mac		\"ec:93:ed:01:00:00\"",
+	}
+
+	overlap := Rule{
+		Name: "no-overlap",
+		Check: func(c *Config) []Violation {
+			prot := c.ActiveEntries("sym.prot.ipv4")
+			raw := c.ActiveEntries("sym.raw.ipv4")
+			if len(prot) == 0 || len(raw) == 0 {
+				return nil
+			}
+			a, _ := netip.ParsePrefix(prot[0].Value)
+			b, _ := netip.ParsePrefix(raw[0].Value)
+			if a.Masked().Overlaps(b.Masked()) {
+				return []Violation{Involve("prefixes overlap", prot[0], raw[0])}
+			}
+			return nil
+		},
+	}
+	sflow := Rule{
+		Name: "sflow-rate",
+		Check: func(c *Config) []Violation {
+			var res []Violation
+			for _, rate := range c.ActiveEntries("sflow.*.rate") {
+				speedKey := strings.TrimSuffix(rate.Name(), ".rate") + ".speed"
+				for _, speed := range c.ActiveEntries(speedKey) {
+					if speed.Value == "0" && rate.Value != "0" {
+						res = append(res, Involve("rate must be zero when speed is zero", rate, speed))
+					}
+				}
+			}
+			return res
+		},
+	}
+
+	schema := NewSchema(&KeySpec{Name: "sync-neighbour", Multiple: true})
+	schema.AddRule(
+		overlap,
+		sflow,
+		WithinPrefix("sync-neighbour", "sym.*.ipv4", "asym.*.ipv4"),
+		Requires("service.ipv4", "service.vlan"),
+		Conflicts("sym.raw.ipv4", "asym.raw.ipv4"),
+		UniqueAcross("mac", "nh.mac"),
+	)
+
+	file := NewConfig()
+	file.SetSchema(schema)
+	file.FromStrings(testData)
+
+	err := file.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("unexpected validation result %v\n", err)
+	}
+
+	expected := []Violation{
+		{"no-overlap", []string{"sym.prot.ipv4", "sym.raw.ipv4"}, []int{1, 2}, "prefixes overlap"},
+		{"sflow-rate", []string{"sflow.drop.rate", "sflow.drop.speed"}, []int{5, 6}, "rate must be zero when speed is zero"},
+		{"within-prefix", []string{"sync-neighbour", "sym.prot.ipv4", "sym.raw.ipv4"}, []int{4, 1, 2}, "10.0.0.1 is outside of sym.*.ipv4, asym.*.ipv4"},
+		{"requires", []string{"service.ipv4", "service.vlan"}, []int{9}, "service.ipv4 is set but service.vlan is not"},
+		{"unique-across", []string{"mac", "nh.mac"}, []int{10, 11}, "value 'ec:93:ed:01:00:00' is used more than once"},
+	}
+	if !reflect.DeepEqual(verr.Violations, expected) {
+		t.Errorf("unexpected violations:\n%v\n", verr)
+	}
+
+	msg := "within-prefix: 10.0.0.1 is outside of sym.*.ipv4, asym.*.ipv4: sync-neighbour (line 4), sym.prot.ipv4 (line 1), sym.raw.ipv4 (line 2)"
+	if verr.Violations[2].Error() != msg {
+		t.Errorf("unexpected message '%s'\n", verr.Violations[2].Error())
+	}
+
+	file.Disable("sym.raw.ipv4")
+	file.Disable("sflow.drop.rate")
+	file.DeleteValue("sync-neighbour", "10.0.0.1")
+	file.Delete("service.ipv4")
+	file.Delete("nh.mac")
+	if err := file.Validate(); err != nil {
+		t.Errorf("unexpected violations after fixes:\n%v\n", err)
+	}
+}
+
+func Test_ConflictsMultiple(t *testing.T) {
+	c := NewConfig()
+	c.SetSchema(NewSchema(&KeySpec{Name: "sync", Multiple: true}))
+	c.Schema().AddRule(Conflicts("sync", "sync-group"))
+	c.FromString("sync 10.0.0.1\nsync 10.0.0.2")
+	if err := c.Validate(); err != nil {
+		t.Errorf("values of one key conflict: %v\n", err)
+	}
+
+	c.Set(ParseString("sync-group edge"))
+	verr, ok := c.Validate().(*ValidationError)
+	if !ok || len(verr.Violations) != 1 || len(verr.Violations[0].Keys) != 3 {
+		t.Errorf("unexpected result %v\n", verr)
+	}
+}
@@ -0,0 +1,199 @@
+package ggo
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// KeySpec describes a key known to a Schema. Name may contain '*'
+// wildcards, each matching within a single dotted segment, so
+// `sflow.*.rate` matches `sflow.drop.rate` but not `sflow.rate`.
+type KeySpec struct {
+	Name     string
+	Multiple bool
+	// Default is the value the application uses when the key is not set.
+	Default string
+	// Description documents the key. It may span several lines.
+	Description string
+	// Type, if set, checks values in Validate and gives their canonical
+	// form in Normalize and Bind.
+	Type Type
+	// Aliases are deprecated names of the key. They are accepted in place
+	// of Name everywhere and reported when parsed.
+	Aliases []string
+	// Secret keys have their values redacted in String, Diff, the journal
+	// and JSON export, and may hold references resolved by Resolve.
+	Secret bool
+}
+
+// Schema lists the keys a config may contain and the rules their values
+// must follow.
+type Schema struct {
+	keys    []*KeySpec
+	exact   map[string]*KeySpec
+	aliases map[string]*KeySpec
+	rules   []Rule
+
+	version  int
+	upgrades map[int]Upgrade
+}
+
+func NewSchema(keys ...*KeySpec) *Schema {
+	s := new(Schema)
+	s.exact = make(map[string]*KeySpec)
+	s.aliases = make(map[string]*KeySpec)
+	s.Add(keys...)
+	return s
+}
+
+func (s *Schema) Add(keys ...*KeySpec) {
+	for _, k := range keys {
+		s.keys = append(s.keys, k)
+		if !isKeyPattern(k.Name) {
+			s.exact[k.Name] = k
+		}
+		for _, alias := range k.Aliases {
+			s.aliases[alias] = k
+		}
+	}
+}
+
+// Clone returns a copy of the schema with copies of its key specs, which
+// can be changed independently. Rules and upgrades are shared.
+func (s *Schema) Clone() *Schema {
+	if s == nil {
+		return nil
+	}
+	res := NewSchema()
+	for _, k := range s.keys {
+		spec := *k
+		spec.Aliases = append([]string(nil), k.Aliases...)
+		res.Add(&spec)
+	}
+	res.rules = append([]Rule(nil), s.rules...)
+	res.version = s.version
+	for _, u := range s.upgrades {
+		res.AddUpgrade(u)
+	}
+	return res
+}
+
+// Canonical returns the current name of a key, and whether name is a
+// deprecated alias of it.
+func (s *Schema) Canonical(name string) (string, bool) {
+	if s == nil {
+		return name, false
+	}
+	if k, exists := s.aliases[name]; exists {
+		return k.Name, true
+	}
+	return name, false
+}
+
+// Lookup returns the spec for name: an exact declaration or alias if there
+// is one, or else the first matching pattern in declaration order.
+func (s *Schema) Lookup(name string) *KeySpec {
+	if s == nil {
+		return nil
+	}
+	if k, exists := s.exact[name]; exists {
+		return k
+	}
+	if k, exists := s.aliases[name]; exists {
+		return k
+	}
+	for _, k := range s.keys {
+		if isKeyPattern(k.Name) && matchKey(k.Name, name) {
+			return k
+		}
+	}
+	return nil
+}
+
+// Keys returns the declared specs in declaration order.
+func (s *Schema) Keys() []*KeySpec {
+	res := make([]*KeySpec, len(s.keys))
+	copy(res, s.keys)
+	return res
+}
+
+func isKeyPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// matchKey matches name against a dotted glob pattern segment by segment.
+func matchKey(pattern string, name string) bool {
+	patternParts := strings.Split(pattern, ".")
+	nameParts := strings.Split(name, ".")
+	if len(patternParts) != len(nameParts) {
+		return false
+	}
+	for i, p := range patternParts {
+		if ok, err := path.Match(p, nameParts[i]); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Sample renders a config listing every declared key commented out with
+// its default value and preceded by its description. Keys are sorted and
+// separated into groups by their first dotted segment. Wildcard keys are
+// left out as they have no concrete name.
+func (s *Schema) Sample() string {
+	specs := make([]*KeySpec, 0, len(s.keys))
+	for _, k := range s.keys {
+		if !isKeyPattern(k.Name) {
+			specs = append(specs, k)
+		}
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+
+	// Descriptions are rendered for a reader without the schema, so that
+	// the sample can be read back by SchemaFromConfig.
+	f := NewConfig()
+
+	lines := make([]string, 0, len(specs))
+	group := ""
+	for i, k := range specs {
+		prefix := strings.SplitN(k.Name, ".", 2)[0]
+		if i > 0 && prefix != group {
+			lines = append(lines, "")
+		}
+		group = prefix
+
+		e := new(ConfigEntry)
+		e.name = k.Name
+		e.Value = k.Default
+		e.DocComment = strings.TrimSpace(k.Description)
+		lines = append(lines, f.entryLines(e, k.Name, "")...)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// SchemaFromConfig declares every key of c, active or not, with its value
+// as the default and its doc comment as the description. Together with
+// Sample it allows a schema to be shipped as a sample config.
+func SchemaFromConfig(c *Config) *Schema {
+	s := NewSchema()
+	for _, k := range c.sortedKeys() {
+		spec := &KeySpec{Name: k, Multiple: c.isMultiple(k)}
+		switch v := c.fields[k].(type) {
+		case *ConfigEntry:
+			spec.Default = v.Value
+			spec.Description = v.DocComment
+		case *ConfigMultiEntry:
+			for _, value := range v.sortedValues() {
+				if doc := v.Entries[value].DocComment; doc != "" {
+					spec.Description = doc
+					break
+				}
+			}
+		}
+		s.Add(spec)
+	}
+	return s
+}
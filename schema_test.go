@@ -0,0 +1,144 @@
+package ggo
+
+import "testing"
+
+func TestMatchKey(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"sflow.*.rate", "sflow.drop.rate", true},
+		{"sflow.*.rate", "sflow.rate", false},
+		{"sflow.*.rate", "sflow.drop.raw.rate", false},
+		{"tb.*.syn.*.speed", "tb.sym.syn.ttl.speed", true},
+		{"eth-*", "eth-0_1", true},
+		{"sync", "sync", true},
+	}
+
+	for _, test := range tests {
+		if got := matchKey(test.pattern, test.name); got != test.match {
+			t.Errorf("matchKey(%s, %s) = %v\n", test.pattern, test.name, got)
+		}
+	}
+}
+
+func TestSchema_CommentedEntries(t *testing.T) {
+	testData := []string{
+		"# see docs",
+		"#sflow.drop.pool 0",
+		"# Bucket configuration",
+		"#tb.sym.syn.ttl.32.speed 1536",
+		"#-unknown.key 1",
+		"sync 239.0.0.3",
+		"#sync 239.1.0.3",
+		"active.unknown 1",
+	}
+
+	schema := NewSchema(
+		&KeySpec{Name: "sflow.*.pool"},
+		&KeySpec{Name: "tb.*.syn.*.32.speed"},
+		&KeySpec{Name: "sync", Multiple: true},
+	)
+
+	file := NewConfig()
+	file.SetSchema(schema)
+	file.FromStrings(testData)
+
+	file.checkEntry(t, false, "sflow.drop.pool", "0", "")
+	file.checkEntry(t, false, "tb.sym.syn.ttl.32.speed", "1536", "")
+	file.checkEntry(t, false, "unknown.key", "1", "")
+	file.checkEntry(t, true, "active.unknown", "1", "")
+	file.checkMultiEntry(t, "sync", map[string]bool{"239.0.0.3": true, "239.1.0.3": false})
+
+	if file.Len() != 0 {
+		t.Errorf("Some fields (%d) left unprocessed %v\n", file.Len(), file.fields)
+	}
+}
+
+func TestConfig_StrictComments(t *testing.T) {
+	testData := []string{
+		"#------------",
+		"# see docs",
+		"#sflow.drop.pool 0",
+		"#-sflow.raw.pool 0",
+		"pcap-speed 220",
+	}
+
+	file := NewConfig()
+	file.SetStrictComments(true)
+	file.FromStrings(testData)
+
+	expected := "pcap-speed 220\n# ------------\n# see docs\n# sflow.drop.pool 0\n#-sflow.raw.pool 0"
+	if got := file.String(); got != expected {
+		t.Errorf("strict config written as:\n%s\n", got)
+	}
+
+	parsed := file.CopyScheme()
+	parsed.FromString(file.String())
+	if got := parsed.String(); got != expected {
+		t.Errorf("strict config read back as:\n%s\n", got)
+	}
+
+	file.checkEntry(t, false, "sflow.raw.pool", "0", "")
+	file.checkEntry(t, true, "pcap-speed", "220", "")
+
+	if file.Len() != 0 {
+		t.Errorf("Some fields (%d) left unprocessed %v\n", file.Len(), file.fields)
+	}
+}
+
+func TestSchema_Sample(t *testing.T) {
+	schema := NewSchema(
+		&KeySpec{Name: "sflow.drop.pool", Default: "0", Description: "Sampling pool for dropped packets"},
+		&KeySpec{Name: "sflow.drop.rate", Default: "1000"},
+		&KeySpec{Name: "pcap-speed", Default: "220", Description: "Capture speed\nin packets per second"},
+		&KeySpec{Name: "sync", Multiple: true, Description: "Sync multicast group"},
+		&KeySpec{Name: "vlan", Default: "1", Description: "docs\n\nsee the manual"},
+		&KeySpec{Name: "tb.*.syn.*.speed", Default: "0"},
+	)
+
+	expected := "## Capture speed\n" +
+		"## in packets per second\n" +
+		"# pcap-speed 220\n" +
+		"\n" +
+		"# Sampling pool for dropped packets\n" +
+		"# sflow.drop.pool 0\n" +
+		"# sflow.drop.rate 1000\n" +
+		"\n" +
+		"# Sync multicast group\n" +
+		"# sync\n" +
+		"\n" +
+		"## docs\n" +
+		"##\n" +
+		"## see the manual\n" +
+		"# vlan 1\n"
+
+	got := schema.Sample()
+	if got != expected {
+		t.Errorf("unexpected sample:\n%s\n", got)
+	}
+
+	file := NewConfig()
+	file.SetSchema(schema)
+	file.FromString(got)
+	if e := file.Get("pcap-speed").(*ConfigEntry); e.IsActive || e.Value != "220" || e.DocComment != "Capture speed\nin packets per second" {
+		t.Errorf("sample read back as %v\n", e)
+	}
+	if e := file.Get("sync").(*ConfigMultiEntry).Get(""); e == nil || e.IsActive {
+		t.Errorf("sample read back as %v\n", file.Get("sync"))
+	}
+	if e := file.Get("vlan").(*ConfigEntry); e.DocComment != "docs\n\nsee the manual" {
+		t.Errorf("sample read back as %v\n", e)
+	}
+	if file.Len() != 5 {
+		t.Errorf("sample read back with %d keys\n", file.Len())
+	}
+
+	file = NewConfig()
+	file.SetKeyMultiple("sync", true)
+	file.FromString(got)
+	if SchemaFromConfig(file).Sample() != got {
+		t.Errorf("sample read back without schema as:\n%s\n", SchemaFromConfig(file).Sample())
+	}
+}
@@ -0,0 +1,246 @@
+package ggo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Values of keys the schema marks Secret may refer to their actual value
+// instead of holding it: `file:/run/secrets/x` names a file holding the
+// value and `enc:...` is a value encrypted with EncryptValue. References
+// are resolved by Resolve and Bind.
+const (
+	fileRefPrefix   = "file:"
+	encryptedPrefix = "enc:"
+)
+
+// redactedValue replaces secret values in String, Diff, the journal and
+// JSON export.
+const redactedValue = "<redacted>"
+
+// SecretKeySize is the size of the keys used to encrypt values.
+const SecretKeySize = 32
+
+// SetKeySecret sets whether values of name are secret, for keys the
+// schema does not mark Secret.
+func (f *Config) SetKeySecret(name string, secret bool) {
+	if secret {
+		f.secretList[name] = true
+	} else {
+		delete(f.secretList, name)
+	}
+}
+
+// IsSecret reports whether values of name are redacted and may hold
+// references: the schema marks the key Secret or SetKeySecret set it.
+func (f *Config) IsSecret(name string) bool {
+	if f.secretList[name] {
+		return true
+	}
+	k := f.schema.Lookup(name)
+	return k != nil && k.Secret
+}
+
+// SetSecretKey sets the key decrypting `enc:` values.
+func (f *Config) SetSecretKey(key []byte) error {
+	if len(key) != SecretKeySize {
+		return fmt.Errorf("secret key must be %d bytes", SecretKeySize)
+	}
+	f.secretKey = append([]byte(nil), key...)
+	return nil
+}
+
+// ParseSecretKey reads a key as stored in a key file: either its raw
+// bytes or their base64 encoding.
+func ParseSecretKey(data []byte) ([]byte, error) {
+	if len(data) == SecretKeySize {
+		return data, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != SecretKeySize {
+		return nil, fmt.Errorf("secret key must be %d bytes, raw or base64", SecretKeySize)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptValue encrypts value with AES-256-GCM, returning it in the
+// `enc:` form a config can hold.
+func EncryptValue(key []byte, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue decrypts a value returned by EncryptValue.
+func DecryptValue(key []byte, value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return "", errors.New("value is not encrypted")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value[len(encryptedPrefix):])
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is truncated")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt value: wrong key or corrupted value")
+	}
+	return string(plain), nil
+}
+
+func isSecretReference(value string) bool {
+	return strings.HasPrefix(value, fileRefPrefix) || strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Resolve returns the value of name like Lookup does, with secret
+// references resolved.
+func (f *Config) Resolve(name string) (string, error) {
+	value, _, found := f.Lookup(name)
+	if !found {
+		return "", fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	return f.resolveValue(name, value)
+}
+
+// resolveValue unquotes value and, for secret keys, resolves a reference.
+func (f *Config) resolveValue(name string, value string) (string, error) {
+	value = unquote(value)
+	if !f.IsSecret(name) {
+		return value, nil
+	}
+
+	switch {
+	case strings.HasPrefix(value, fileRefPrefix):
+		data, err := os.ReadFile(value[len(fileRefPrefix):])
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, encryptedPrefix):
+		if f.secretKey == nil {
+			return "", fmt.Errorf("%s: no secret key to decrypt the value", name)
+		}
+		plain, err := DecryptValue(f.secretKey, value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		return plain, nil
+	}
+	return value, nil
+}
+
+// redacted returns f, or when it holds secret keys a copy of it with
+// their values redacted.
+func (f *Config) redacted() *Config {
+	res := f
+	for k, e := range f.fields {
+		if !f.IsSecret(k) {
+			continue
+		}
+		if res == f {
+			res = f.Clone()
+		}
+		res.fields[k] = redactField(e)
+	}
+	return res
+}
+
+// redactField returns a copy of e with its values redacted.
+func redactField(e ConfigEntryInterface) ConfigEntryInterface {
+	if e == nil {
+		return nil
+	}
+	e = copyField(e)
+	switch v := e.(type) {
+	case *ConfigEntry:
+		if v.Value != "" {
+			v.Value = redactedValue
+		}
+	case *ConfigMultiEntry:
+		for _, entry := range v.Entries {
+			entry.Value = redactedValue
+		}
+	}
+	return e
+}
+
+// MarshalJSON exports the active values as an object mapping each key to
+// its value, or to an array of values for multi-valued keys. Values of
+// secret keys are redacted.
+func (f *Config) MarshalJSON() ([]byte, error) {
+	res := make(map[string]interface{}, len(f.fields))
+	for _, k := range f.sortedKeys() {
+		var values []string
+		for _, e := range f.ActiveEntries(k) {
+			value := unquote(e.Value)
+			if f.IsSecret(k) {
+				value = redactedValue
+			}
+			values = append(values, value)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if _, multi := f.fields[k].(*ConfigMultiEntry); multi {
+			res[k] = values
+		} else {
+			res[k] = values[0]
+		}
+	}
+	return json.Marshal(res)
+}
+
+// Diff lists the keys that differ between a and b, as changes turning a
+// into b, in key order. Values of keys either config marks secret are
+// redacted; their changes are still listed.
+func Diff(a, b *Config) []Change {
+	var res []Change
+	for _, k := range unionKeys(a.fields, b.fields) {
+		before, after := a.fields[k], b.fields[k]
+		if renderField(before) == renderField(after) {
+			continue
+		}
+
+		c := Change{Op: "set", Key: k}
+		switch {
+		case before == nil:
+			c.Op = "add"
+		case after == nil:
+			c.Op = "delete"
+		}
+		if a.IsSecret(k) || b.IsSecret(k) {
+			before, after = redactField(before), redactField(after)
+		}
+		c.Old, c.New = renderField(before), renderField(after)
+		res = append(res, c)
+	}
+	return res
+}
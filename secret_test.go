@@ -0,0 +1,147 @@
+package ggo
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func secretConfig() *Config {
+	c := NewConfig()
+	c.SetSchema(NewSchema(
+		&KeySpec{Name: "snmp.community", Secret: true},
+		&KeySpec{Name: "api.tokens", Secret: true, Multiple: true},
+		&KeySpec{Name: "api.port", Type: TypeInt},
+	))
+	c.FromString("snmp.community public\napi.tokens t1\napi.tokens t2\napi.port 8080")
+	return c
+}
+
+func Test_SecretRedaction(t *testing.T) {
+	c := secretConfig()
+	expected := "api.port 8080\napi.tokens <redacted>\napi.tokens <redacted>\nsnmp.community <redacted>"
+	if s := c.String(); s != expected {
+		t.Errorf("String = %q\n", s)
+	}
+	if v, _, _ := c.Lookup("snmp.community"); v != "public" {
+		t.Errorf("redaction changed the value to '%s'\n", v)
+	}
+
+	path := filepath.Join(t.TempDir(), "ggo.conf")
+	c.Write(path)
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "snmp.community public") {
+		t.Errorf("Write redacted the file: %q\n", data)
+	}
+
+	data, err := json.Marshal(c)
+	var exported map[string]interface{}
+	json.Unmarshal(data, &exported)
+	if err != nil || exported["api.port"] != "8080" || exported["snmp.community"] != "<redacted>" ||
+		len(exported["api.tokens"].([]interface{})) != 2 || exported["api.tokens"].([]interface{})[0] != "<redacted>" {
+		t.Errorf("MarshalJSON = %s, %v\n", data, err)
+	}
+
+	other := secretConfig()
+	other.Set(ParseString("snmp.community private"))
+	other.Set(ParseString("api.port 8081"))
+	diff := Diff(c, other)
+	if len(diff) != 2 || diff[0].Key != "api.port" || diff[0].New != "api.port 8081" ||
+		diff[1].Key != "snmp.community" || diff[1].Old != "snmp.community <redacted>" || diff[1].New != "snmp.community <redacted>" {
+		t.Errorf("Diff = %+v\n", diff)
+	}
+
+	var log bytes.Buffer
+	c.EnableJournal().LogTo(&log)
+	c.Set(ParseString("snmp.community private"))
+	if strings.Contains(log.String(), "private") || strings.Contains(log.String(), "public") {
+		t.Errorf("journal leaked a secret: %s\n", log.String())
+	}
+}
+
+func Test_SetKeySecret(t *testing.T) {
+	c := NewConfig()
+	c.SetKeySecret("password", true)
+	c.FromStrings([]string{"password hunter2", "port 161"})
+	if c.String() != "password <redacted>\nport 161" || !c.CopyScheme().IsSecret("password") {
+		t.Errorf("unexpected redaction: %q\n", c.String())
+	}
+
+	c.SetKeySecret("password", false)
+	if c.IsSecret("password") {
+		t.Errorf("password still secret\n")
+	}
+}
+
+func Test_SecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "community")
+	os.WriteFile(secretFile, []byte("from-file\n"), 0600)
+
+	key := bytes.Repeat([]byte{7}, SecretKeySize)
+	enc, err := EncryptValue(key, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := secretConfig()
+	c.Set(ParseString("snmp.community file:" + secretFile))
+	if v, err := c.Resolve("snmp.community"); err != nil || v != "from-file" {
+		t.Errorf("Resolve = '%s', %v\n", v, err)
+	}
+
+	c.Set(ParseString("snmp.community \"" + enc + "\""))
+	if _, err := c.Resolve("snmp.community"); err == nil {
+		t.Errorf("decrypted without a key\n")
+	}
+	if err := c.SetSecretKey(key[:16]); err == nil {
+		t.Errorf("short key accepted\n")
+	}
+	c.SetSecretKey(key)
+	if v, err := c.Resolve("snmp.community"); err != nil || v != "s3cret" {
+		t.Errorf("Resolve = '%s', %v\n", v, err)
+	}
+
+	var bound struct {
+		Community string `ggo:"snmp.community"`
+	}
+	if err := c.Bind(&bound); err != nil || bound.Community != "s3cret" {
+		t.Errorf("Bind = '%s', %v\n", bound.Community, err)
+	}
+
+	c.SetSecretKey(bytes.Repeat([]byte{8}, SecretKeySize))
+	if _, err := c.Resolve("snmp.community"); err == nil {
+		t.Errorf("decrypted with a wrong key\n")
+	}
+
+	// References are only resolved for secret keys.
+	c.Set(ParseString("api.port file:" + secretFile))
+	if v, _ := c.Resolve("api.port"); v != "file:"+secretFile {
+		t.Errorf("reference resolved for a plain key: '%s'\n", v)
+	}
+
+	// Typed getters resolve references too.
+	portFile := filepath.Join(dir, "port")
+	os.WriteFile(portFile, []byte("8443\n"), 0600)
+	c.Set(ParseString("api.port file:" + portFile))
+	c.SetKeySecret("api.port", true)
+	if v, err := c.GetInt("api.port"); err != nil || v != 8443 {
+		t.Errorf("GetInt = %d, %v\n", v, err)
+	}
+}
+
+func Test_ParseSecretKey(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, SecretKeySize)
+	if k, err := ParseSecretKey(key); err != nil || !bytes.Equal(k, key) {
+		t.Errorf("raw key rejected: %v\n", err)
+	}
+	encoded := []byte("AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=\n")
+	if k, err := ParseSecretKey(encoded); err != nil || !bytes.Equal(k, key) {
+		t.Errorf("base64 key rejected: %v\n", err)
+	}
+	if _, err := ParseSecretKey([]byte("short")); err == nil {
+		t.Errorf("invalid key accepted\n")
+	}
+}
@@ -0,0 +1,190 @@
+package ggo
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EntryOption sets optional fields of an entry made by NewEntry.
+type EntryOption func(e *ConfigEntry)
+
+// Disabled makes the entry commented out.
+func Disabled() EntryOption {
+	return func(e *ConfigEntry) { e.IsActive = false }
+}
+
+// WithComment sets the inline comment written after the value.
+func WithComment(comment string) EntryOption {
+	return func(e *ConfigEntry) { e.Comment = comment }
+}
+
+// WithDoc sets the comment lines written above the entry.
+func WithDoc(doc string) EntryOption {
+	return func(e *ConfigEntry) { e.DocComment = doc }
+}
+
+// NewEntry makes an active entry of name holding value, quoting the value
+// when it would not read back as a single value. Line breaks in value are
+// replaced with spaces. It returns nil if name is not a valid key: empty,
+// or containing whitespace or starting with '#' or '"'.
+func NewEntry(name string, value string, opts ...EntryOption) *ConfigEntry {
+	if name == "" || strings.ContainsAny(name, " \t\r\n{}[") || name[0] == '#' || name[0] == '"' {
+		return nil
+	}
+	e := &ConfigEntry{name: name, Value: quoteValue(value), IsActive: true}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// quoteValue returns value as written in a config. Values ending with a
+// backslash are quoted so that they are not read as continued lines, and
+// values starting with a brace or a bracket so that they are not read as
+// blocks. Quoted values escape quotes and backslashes with a backslash.
+func quoteValue(value string) string {
+	value = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(value)
+	if value == "" || !strings.ContainsAny(value, " \t") && !strings.ContainsRune("#\"{}[", rune(value[0])) && value[len(value)-1] != '\\' {
+		return value
+	}
+	return "\"" + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + "\""
+}
+
+// encodeValue renders v, a value of type t, in the canonical form of the
+// schema type of name if it has one, or else of t.
+func (f *Config) encodeValue(name string, t Type, v interface{}) (string, error) {
+	value := t.Format(v)
+	if spec := f.schema.Lookup(name); spec != nil && spec.Type != nil {
+		parsed, err := spec.Type.Parse(unquote(value))
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		value = spec.Type.Format(parsed)
+	}
+	return value, nil
+}
+
+// setTyped sets name to v encoded as a value of t, keeping the comments of
+// the entry it replaces.
+func (f *Config) setTyped(name string, t Type, v interface{}) error {
+	value, err := f.encodeValue(name, t, v)
+	if err != nil {
+		return err
+	}
+	e := NewEntry(name, "")
+	if e == nil {
+		return fmt.Errorf("invalid key '%s'", name)
+	}
+	e.Value = value
+	if prev, ok := f.Get(name).(*ConfigEntry); ok {
+		e.Comment, e.DocComment = prev.Comment, prev.DocComment
+	}
+	f.Set(e)
+	return nil
+}
+
+func (f *Config) SetInt(name string, v int64) error {
+	return f.setTyped(name, TypeInt, v)
+}
+
+func (f *Config) SetIP(name string, v netip.Addr) error {
+	return f.setTyped(name, TypeIP, v)
+}
+
+func (f *Config) SetPrefix(name string, v netip.Prefix) error {
+	return f.setTyped(name, TypePrefix, v)
+}
+
+func (f *Config) SetMAC(name string, v net.HardwareAddr) error {
+	return f.setTyped(name, TypeMAC, v)
+}
+
+func (f *Config) SetVLAN(name string, v uint16) error {
+	return f.setTyped(name, TypeVLAN, v)
+}
+
+func (f *Config) SetRate(name string, v uint64) error {
+	return f.setTyped(name, TypeRate, v)
+}
+
+func (f *Config) SetDuration(name string, v time.Duration) error {
+	return f.setTyped(name, TypeDuration, v)
+}
+
+// stringType formats strings, quoting them when needed.
+type stringType struct{}
+
+func (t stringType) Parse(value string) (interface{}, error) {
+	return value, nil
+}
+
+func (t stringType) Format(v interface{}) string {
+	return quoteValue(v.(string))
+}
+
+// typeOf returns the type formatting the Go value v.
+func typeOf(v interface{}) (Type, interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		return stringType{}, x, nil
+	case int:
+		return TypeInt, int64(x), nil
+	case int8:
+		return TypeInt, int64(x), nil
+	case int16:
+		return TypeInt, int64(x), nil
+	case int32:
+		return TypeInt, int64(x), nil
+	case int64:
+		return TypeInt, x, nil
+	case uint, uint8, uint16, uint32, uint64:
+		return stringType{}, fmt.Sprint(x), nil
+	case bool:
+		return stringType{}, strconv.FormatBool(x), nil
+	case netip.Addr:
+		return TypeIP, x, nil
+	case netip.Prefix:
+		return TypePrefix, x, nil
+	case net.HardwareAddr:
+		return TypeMAC, x, nil
+	case time.Duration:
+		return TypeDuration, x, nil
+	case fmt.Stringer:
+		return stringType{}, x.String(), nil
+	}
+	return nil, nil, fmt.Errorf("unsupported value type %T", v)
+}
+
+// AddValue adds v to the values of the multi-valued key name, encoded
+// canonically like the typed setters do. Strings, integers, booleans,
+// netip.Addr, netip.Prefix, net.HardwareAddr, time.Duration and
+// fmt.Stringer values are supported. Adding a value already present keeps
+// its entry.
+func (f *Config) AddValue(name string, v interface{}) error {
+	name = f.canonicalName(name)
+	if !f.isMultiple(name) {
+		return fmt.Errorf("%s: %w", name, ErrNotMultiple)
+	}
+	t, x, err := typeOf(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	value, err := f.encodeValue(name, t, x)
+	if err != nil {
+		return err
+	}
+
+	if m, ok := f.fields[name].(*ConfigMultiEntry); ok && m.Get(value) != nil {
+		return nil
+	}
+	e := NewEntry(name, "")
+	if e == nil {
+		return fmt.Errorf("invalid key '%s'", name)
+	}
+	e.Value = value
+	return f.Add(e)
+}
@@ -0,0 +1,116 @@
+package ggo
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func Test_NewEntry(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected string
+	}{
+		{"plain", "key plain"},
+		{"two words", `key "two words"`},
+		{"#hash", `key "#hash"`},
+		{`say "hi"`, `key "say \"hi\""`},
+		{"multi\nline", `key "multi line"`},
+		{"", "key"},
+		{`C:\`, `key "C:\\"`},
+		{`a\b c`, `key "a\\b c"`},
+		{"{x", `key "{x"`},
+		{"}", `key "}"`},
+		{"[x]", `key "[x]"`},
+	}
+	for _, tc := range cases {
+		e := NewEntry("key", tc.value)
+		if e.String() != tc.expected {
+			t.Errorf("'%s' written as '%s'\n", tc.value, e.String())
+		}
+		if tc.value != "" && tc.value != "multi\nline" {
+			if back := ParseString(e.String()); back == nil || unquote(back.Value) != tc.value {
+				t.Errorf("'%s' does not read back\n", tc.value)
+			}
+		}
+	}
+
+	c := NewConfig()
+	c.Set(NewEntry("path", `C:\`))
+	c.Set(NewEntry("vlan", "10"))
+	c.FromString(c.String())
+	c.checkEntry(t, true, "path", `"C:\\"`, "")
+	c.checkEntry(t, true, "vlan", "10", "")
+
+	c.Set(NewEntry("open", "{x"))
+	c.Set(NewEntry("close", "}"))
+	c.Set(NewEntry("section", "[x]"))
+	c.Set(NewEntry("vlan", "10"))
+	c.FromString(c.String())
+	c.checkEntry(t, true, "open", `"{x"`, "")
+	c.checkEntry(t, true, "close", `"}"`, "")
+	c.checkEntry(t, true, "section", `"[x]"`, "")
+	c.checkEntry(t, true, "vlan", "10", "")
+	if c.Len() != 0 {
+		t.Errorf("Some fields (%d) left unprocessed %v\n", c.Len(), c.fields)
+	}
+
+	e := NewEntry("vlan", "10", Disabled(), WithComment("edge"), WithDoc("access vlan"))
+	if e.Name() != "vlan" || e.IsActive || e.Comment != "edge" || e.DocComment != "access vlan" {
+		t.Errorf("options not applied: %+v\n", e)
+	}
+	for _, name := range []string{"", "two words", "#key", `"key`, "[k]", "a{", "}"} {
+		if NewEntry(name, "v") != nil {
+			t.Errorf("invalid key '%s' accepted\n", name)
+		}
+	}
+}
+
+func Test_TypedSetters(t *testing.T) {
+	c := NewConfig()
+	c.SetSchema(NewSchema(
+		&KeySpec{Name: "speed", Type: TypeRate},
+		&KeySpec{Name: "vlan", Type: TypeVLAN},
+		&KeySpec{Name: "peers", Multiple: true, Type: TypeIP},
+		&KeySpec{Name: "tags", Multiple: true},
+	))
+	c.FromString("# Port speed\nspeed 10 # bits")
+
+	if err := c.SetInt("speed", 2000000); err != nil {
+		t.Errorf("SetInt: %v\n", err)
+	}
+	c.SetPrefix("net", netip.MustParsePrefix("198.18.1.0/24"))
+	c.SetMAC("mac", net.HardwareAddr{0, 0x1b, 0x21, 0xa, 0xb, 0xc})
+	c.SetDuration("timeout", 90*time.Second)
+	if err := c.SetInt("vlan", 5000); err == nil {
+		t.Errorf("invalid VLAN accepted\n")
+	}
+
+	expected := `mac "00:1b:21:0a:0b:0c"
+net 198.18.1.0/24
+# Port speed
+speed 2M # bits
+timeout 1m30s`
+	if s := c.String(); s != expected {
+		t.Errorf("config is\n%s\n", s)
+	}
+	if d, err := c.GetDuration("timeout"); err != nil || d != 90*time.Second {
+		t.Errorf("GetDuration = %v, %v\n", d, err)
+	}
+
+	c.AddValue("peers", netip.MustParseAddr("10.0.0.1"))
+	c.AddValue("peers", "10.0.0.2")
+	c.AddValue("peers", "10.0.0.1")
+	c.AddValue("tags", "core uplink")
+	c.AddValue("tags", 7)
+	if err := c.AddValue("peers", "nowhere"); err == nil {
+		t.Errorf("invalid IP accepted\n")
+	}
+	if err := c.AddValue("speed", 1); !errors.Is(err, ErrNotMultiple) {
+		t.Errorf("AddValue to a single-valued key: %v\n", err)
+	}
+	c.checkMultiEntry(t, "peers", map[string]bool{"10.0.0.1": true, "10.0.0.2": true})
+	c.checkMultiEntry(t, "tags", map[string]bool{`"core uplink"`: true, "7": true})
+}
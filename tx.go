@@ -0,0 +1,231 @@
+package ggo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Tx stages edits made within Config.Update. Its methods act like the
+// Config methods of the same name on a private copy of the config being
+// updated. Saving, locking and loading are left to Update.
+type Tx struct {
+	c *Config
+}
+
+func (tx *Tx) Get(name string) ConfigEntryInterface {
+	return tx.c.Get(name)
+}
+
+func (tx *Tx) Lookup(name string) (value string, isSet bool, found bool) {
+	return tx.c.Lookup(name)
+}
+
+func (tx *Tx) LookupValues(name string) (values []string, isSet bool) {
+	return tx.c.LookupValues(name)
+}
+
+func (tx *Tx) Resolve(name string) (string, error) {
+	return tx.c.Resolve(name)
+}
+
+func (tx *Tx) SortedKeys() []string {
+	return tx.c.SortedKeys()
+}
+
+func (tx *Tx) Len() int {
+	return tx.c.Len()
+}
+
+func (tx *Tx) Set(e *ConfigEntry) {
+	tx.c.Set(e)
+}
+
+func (tx *Tx) Add(e *ConfigEntry) error {
+	return tx.c.Add(e)
+}
+
+func (tx *Tx) SetAll(name string, entries ...*ConfigEntry) error {
+	return tx.c.SetAll(name, entries...)
+}
+
+func (tx *Tx) Replace(e *ConfigEntry) bool {
+	return tx.c.Replace(e)
+}
+
+func (tx *Tx) Delete(name string) ConfigEntryInterface {
+	return tx.c.Delete(name)
+}
+
+func (tx *Tx) DeleteValue(name string, value string) *ConfigEntry {
+	return tx.c.DeleteValue(name, value)
+}
+
+func (tx *Tx) Enable(name string) bool {
+	return tx.c.Enable(name)
+}
+
+func (tx *Tx) Disable(name string) bool {
+	return tx.c.Disable(name)
+}
+
+func (tx *Tx) EnableValue(name string, value string) bool {
+	return tx.c.EnableValue(name, value)
+}
+
+func (tx *Tx) DisableValue(name string, value string) bool {
+	return tx.c.DisableValue(name, value)
+}
+
+// SetPath sets the file the config is stored in. Save and Update write
+// there. FromFile remembers the name of the file read.
+func (f *Config) SetPath(path string) {
+	f.path = path
+	f.stamp = nil
+}
+
+func (f *Config) Path() string {
+	return f.path
+}
+
+// Update applies the edits made by fn atomically. The edits are staged on
+// a copy of the config, which is then validated and, if the config has a
+// path, written to it like Save does. Only once all of this succeeded are
+// the changes committed to f. If fn, Validate or writing fails, f and its
+// file are left as they were and the error is returned.
+//
+// The journal records a change per key edited, all in one batch that a
+// single Undo reverts.
+func (f *Config) Update(fn func(tx *Tx) error) error {
+	staged := f.Clone()
+	if err := fn(&Tx{staged}); err != nil {
+		return err
+	}
+	if err := staged.Validate(); err != nil {
+		return err
+	}
+	if f.path != "" {
+		if err := staged.save(f.path); err != nil {
+			return err
+		}
+	}
+
+	f.adopt(staged, "update")
+	f.stamp = staged.stamp
+	return nil
+}
+
+// adopt commits the entries of staged, an edited clone of f, journaling
+// the edits as one batch of op changes.
+func (f *Config) adopt(staged *Config, op string) {
+	before := f.fields
+	f.fields = staged.fields
+	f.multipleList = staged.multipleList
+	f.version = staged.version
+	if j := f.journal; j != nil {
+		j.batches++
+		j.batch = j.batches
+		for _, k := range unionKeys(before, f.fields) {
+			f.record(op, k, before[k])
+		}
+		j.batch = 0
+	}
+}
+
+// unionKeys returns the keys of a and b in sorted order.
+func unionKeys(a, b map[string]ConfigEntryInterface) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, exists := a[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Clone returns a deep copy of the config: its entries, schema, settings
+// and the file it was read from. The copy has no journal.
+func (f *Config) Clone() *Config {
+	c := f.copySettings()
+	c.schema = f.schema.Clone()
+	c.version = f.version
+	c.versionLine = f.versionLine
+	c.diagnostics = append([]Diagnostic(nil), f.diagnostics...)
+	c.path = f.path
+	c.source = append([]byte(nil), f.source...)
+	c.raw = append([]string(nil), f.raw...)
+	if f.stamp != nil {
+		stamp := *f.stamp
+		c.stamp = &stamp
+	}
+	c.fields = make(map[string]ConfigEntryInterface, len(f.fields))
+	for k, e := range f.fields {
+		c.fields[k] = e.Copy()
+	}
+	return c
+}
+
+// copySettings returns an empty config with the scheme of f and the
+// settings deciding how f is written and its secrets resolved.
+func (f *Config) copySettings() *Config {
+	c := f.CopyScheme()
+	c.wrapWidth = f.wrapWidth
+	c.layout = f.layout
+	c.layoutDepth = f.layoutDepth
+	c.checksum = f.checksum
+	c.secretKey = append([]byte(nil), f.secretKey...)
+	return c
+}
+
+// copyField returns a deep copy of a stored key, or nil.
+func copyField(e ConfigEntryInterface) ConfigEntryInterface {
+	if e == nil {
+		return nil
+	}
+	return e.Copy()
+}
+
+// fileContent renders the config as written to files.
+func (f *Config) fileContent() []byte {
+	var buf bytes.Buffer
+	for _, line := range f.fileLines() {
+		buf.WriteString(line + "\n")
+	}
+	return buf.Bytes()
+}
+
+// writeAtomic writes data to a temporary file next to path and renames it
+// over path, so that readers see either the old or the new content. The
+// permissions of an existing file are kept.
+func writeAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,90 @@
+package ggo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func txConfig(t *testing.T) (*Config, string) {
+	path := filepath.Join(t.TempDir(), "ggo.conf")
+	if err := os.WriteFile(path, []byte("vlan 10\nsync.neighbour 10.0.0.1\nsync.neighbour 10.0.0.2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	c := NewConfig()
+	c.SetSchema(NewSchema(
+		&KeySpec{Name: "vlan", Type: TypeVLAN},
+		&KeySpec{Name: "sync.neighbour", Multiple: true, Type: TypeIP},
+	))
+	if err := c.FromFile(file); err != nil {
+		t.Fatal(err)
+	}
+	return c, path
+}
+
+func Test_UpdateCommit(t *testing.T) {
+	c, path := txConfig(t)
+	err := c.Update(func(tx *Tx) error {
+		tx.Set(ParseString("vlan 20"))
+		tx.DeleteValue("sync.neighbour", "10.0.0.1")
+		tx.Get("sync.neighbour").(*ConfigMultiEntry).Replace(ParseString("sync.neighbour 10.0.0.3"))
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Update failed: %v\n", err)
+	}
+
+	expected := "sync.neighbour 10.0.0.2\nsync.neighbour 10.0.0.3\nvlan 20"
+	if s := c.String(); s != expected {
+		t.Errorf("config is %q\n", s)
+	}
+	if data, _ := os.ReadFile(path); string(data) != expected+"\n" {
+		t.Errorf("file is %q\n", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("file mode changed to %v\n", info.Mode())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v\n", entries)
+	}
+}
+
+func Test_UpdateRollback(t *testing.T) {
+	c, path := txConfig(t)
+	before := c.String()
+	data, _ := os.ReadFile(path)
+
+	failure := errors.New("abort")
+	err := c.Update(func(tx *Tx) error {
+		tx.Set(ParseString("vlan 20"))
+		tx.Get("sync.neighbour").(*ConfigMultiEntry).Get("10.0.0.1").Value = "10.0.0.9"
+		return failure
+	})
+	if err != failure {
+		t.Errorf("Update = %v\n", err)
+	}
+
+	err = c.Update(func(tx *Tx) error {
+		tx.Set(ParseString("vlan 20"))
+		tx.Get("sync.neighbour").(*ConfigMultiEntry).Replace(ParseString("sync.neighbour 10.0.0.256"))
+		return nil
+	})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Errorf("invalid update not rejected: %v\n", err)
+	}
+
+	if s := c.String(); s != before {
+		t.Errorf("config changed to %q\n", s)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(data) {
+		t.Errorf("file changed to %q\n", after)
+	}
+}
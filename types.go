@@ -0,0 +1,339 @@
+package ggo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type parses, validates and formats the values of a key. Values are
+// passed to Parse without surrounding quotes.
+type Type interface {
+	Parse(value string) (interface{}, error)
+	// Format renders a value returned by Parse in canonical form.
+	Format(v interface{}) string
+}
+
+// PrefixType holds an IP prefix such as `198.18.1.2/24` as a netip.Prefix.
+// Unless HostBits is set, the address must be the network address.
+type PrefixType struct {
+	HostBits bool
+}
+
+func (t PrefixType) Parse(value string) (interface{}, error) {
+	p, err := netip.ParsePrefix(value)
+	if err != nil {
+		return nil, err
+	}
+	if !t.HostBits && p.Masked() != p {
+		return nil, fmt.Errorf("prefix %s has host bits set", value)
+	}
+	return p, nil
+}
+
+func (t PrefixType) Format(v interface{}) string {
+	return v.(netip.Prefix).String()
+}
+
+// IPType holds an IPv4 or IPv6 address as a netip.Addr, optionally
+// restricted to unicast or multicast addresses.
+type IPType struct {
+	Unicast   bool
+	Multicast bool
+}
+
+func (t IPType) Parse(value string) (interface{}, error) {
+	a, err := netip.ParseAddr(value)
+	if err != nil {
+		return nil, err
+	}
+	if t.Multicast && !a.IsMulticast() {
+		return nil, fmt.Errorf("%s is not a multicast address", value)
+	}
+	if t.Unicast && (a.IsMulticast() || a.IsUnspecified() || a == netip.AddrFrom4([4]byte{255, 255, 255, 255})) {
+		return nil, fmt.Errorf("%s is not a unicast address", value)
+	}
+	return a, nil
+}
+
+func (t IPType) Format(v interface{}) string {
+	return v.(netip.Addr).String()
+}
+
+// VLANType holds a VLAN ID from 1 to 4094 as a uint16.
+type VLANType struct{}
+
+func (t VLANType) Parse(value string) (interface{}, error) {
+	n, err := strconv.ParseUint(value, 10, 16)
+	if err != nil || n < 1 || n > 4094 {
+		return nil, fmt.Errorf("invalid VLAN ID '%s'", value)
+	}
+	return uint16(n), nil
+}
+
+func (t VLANType) Format(v interface{}) string {
+	return strconv.FormatUint(uint64(v.(uint16)), 10)
+}
+
+// MACType holds a MAC address as a net.HardwareAddr. Values are accepted
+// with or without quotes; Quoted selects the canonical form.
+type MACType struct {
+	Quoted bool
+}
+
+func (t MACType) Parse(value string) (interface{}, error) {
+	mac, err := net.ParseMAC(value)
+	if err != nil {
+		return nil, err
+	}
+	return mac, nil
+}
+
+func (t MACType) Format(v interface{}) string {
+	if t.Quoted {
+		return "\"" + v.(net.HardwareAddr).String() + "\""
+	}
+	return v.(net.HardwareAddr).String()
+}
+
+// RateType holds a bit or packet rate as a uint64. Values may carry a k,
+// M or G suffix, decimal multiples of 1000; the canonical form uses the
+// largest suffix that divides the rate.
+type RateType struct{}
+
+var rateSuffixes = []struct {
+	suffix string
+	factor uint64
+}{
+	{"G", 1000 * 1000 * 1000},
+	{"M", 1000 * 1000},
+	{"k", 1000},
+}
+
+func (t RateType) Parse(value string) (interface{}, error) {
+	factor := uint64(1)
+	for _, s := range rateSuffixes {
+		if strings.HasSuffix(value, s.suffix) {
+			value, factor = strings.TrimSuffix(value, s.suffix), s.factor
+			break
+		}
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate '%s'", value)
+	}
+	if n > ^uint64(0)/factor {
+		return nil, fmt.Errorf("rate '%s' is out of range", value)
+	}
+	return n * factor, nil
+}
+
+func (t RateType) Format(v interface{}) string {
+	n := v.(uint64)
+	for _, s := range rateSuffixes {
+		if n != 0 && n%s.factor == 0 {
+			return strconv.FormatUint(n/s.factor, 10) + s.suffix
+		}
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+// IntType holds an integer as an int64, within [Min, Max] unless both are
+// zero.
+type IntType struct {
+	Min int64
+	Max int64
+}
+
+func (t IntType) Parse(value string) (interface{}, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid integer '%s'", value)
+	}
+	if (t.Min != 0 || t.Max != 0) && (n < t.Min || n > t.Max) {
+		return nil, fmt.Errorf("%d is out of range [%d, %d]", n, t.Min, t.Max)
+	}
+	return n, nil
+}
+
+func (t IntType) Format(v interface{}) string {
+	return strconv.FormatInt(v.(int64), 10)
+}
+
+// DurationType holds a duration such as `1m30s` as a time.Duration.
+type DurationType struct{}
+
+func (t DurationType) Parse(value string) (interface{}, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration '%s'", value)
+	}
+	return d, nil
+}
+
+func (t DurationType) Format(v interface{}) string {
+	return v.(time.Duration).String()
+}
+
+var (
+	TypeInt         Type = IntType{}
+	TypePrefix      Type = PrefixType{HostBits: true}
+	TypeNetwork     Type = PrefixType{}
+	TypeIP          Type = IPType{}
+	TypeUnicastIP   Type = IPType{Unicast: true}
+	TypeMulticastIP Type = IPType{Multicast: true}
+	TypeVLAN        Type = VLANType{}
+	TypeMAC         Type = MACType{Quoted: true}
+	TypeRate        Type = RateType{}
+	TypeDuration    Type = DurationType{}
+)
+
+// ErrNotFound is returned by typed getters for keys with no value at all.
+var ErrNotFound = errors.New("key not found")
+
+// ErrNotMultiple is returned when adding values to a key that is not
+// multi-valued.
+var ErrNotMultiple = errors.New("key is not multi-valued")
+
+// typed parses the value of name, as returned by Lookup with references
+// of secret keys resolved, with t.
+func (f *Config) typed(name string, t Type) (interface{}, error) {
+	value, _, found := f.Lookup(name)
+	if !found {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	value, err := f.resolveValue(f.canonicalName(name), value)
+	if err != nil {
+		return nil, err
+	}
+	v, err := t.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return v, nil
+}
+
+func (f *Config) GetInt(name string) (int64, error) {
+	v, err := f.typed(name, TypeInt)
+	if err != nil {
+		return 0, err
+	}
+	return v.(int64), nil
+}
+
+func (f *Config) GetPrefix(name string) (netip.Prefix, error) {
+	v, err := f.typed(name, TypePrefix)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return v.(netip.Prefix), nil
+}
+
+func (f *Config) GetIP(name string) (netip.Addr, error) {
+	v, err := f.typed(name, TypeIP)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return v.(netip.Addr), nil
+}
+
+func (f *Config) GetVLAN(name string) (uint16, error) {
+	v, err := f.typed(name, TypeVLAN)
+	if err != nil {
+		return 0, err
+	}
+	return v.(uint16), nil
+}
+
+func (f *Config) GetMAC(name string) (net.HardwareAddr, error) {
+	v, err := f.typed(name, TypeMAC)
+	if err != nil {
+		return nil, err
+	}
+	return v.(net.HardwareAddr), nil
+}
+
+func (f *Config) GetRate(name string) (uint64, error) {
+	v, err := f.typed(name, TypeRate)
+	if err != nil {
+		return 0, err
+	}
+	return v.(uint64), nil
+}
+
+func (f *Config) GetDuration(name string) (time.Duration, error) {
+	v, err := f.typed(name, TypeDuration)
+	if err != nil {
+		return 0, err
+	}
+	return v.(time.Duration), nil
+}
+
+// checkTypes reports active values that their key's schema type rejects.
+func (f *Config) checkTypes() []Violation {
+	var res []Violation
+	for _, k := range f.sortedKeys() {
+		spec := f.schema.Lookup(k)
+		if spec == nil || spec.Type == nil {
+			continue
+		}
+		for _, e := range f.ActiveEntries(k) {
+			if spec.Secret && isSecretReference(unquote(e.Value)) {
+				continue
+			}
+			if _, err := spec.Type.Parse(unquote(e.Value)); err != nil {
+				v := Involve(err.Error(), e)
+				v.Rule = "type"
+				res = append(res, v)
+			}
+		}
+	}
+	return res
+}
+
+// Normalize rewrites every value whose key has a schema type in the
+// canonical form of that type. Values the type rejects are left as they
+// are; Validate reports them. Values of a multi-valued key that become
+// equal are merged the way duplicates are when parsing, in sorted order of
+// their original values. Changes are journaled.
+func (f *Config) Normalize() {
+	for _, k := range f.sortedKeys() {
+		spec := f.schema.Lookup(k)
+		if spec == nil || spec.Type == nil {
+			continue
+		}
+
+		var res ConfigEntryInterface
+		switch v := f.fields[k].(type) {
+		case *ConfigEntry:
+			e := v.Copy().(*ConfigEntry)
+			normalizeEntry(e, spec.Type)
+			res = e
+		case *ConfigMultiEntry:
+			m := &ConfigMultiEntry{name: v.name, Entries: make(map[string]*ConfigEntry, len(v.Entries))}
+			for _, value := range v.sortedValues() {
+				e := v.Entries[value].Copy().(*ConfigEntry)
+				normalizeEntry(e, spec.Type)
+				m.ChooseActiveOrReduce(e)
+			}
+			res = m
+		}
+		if renderField(res) == renderField(f.fields[k]) {
+			continue
+		}
+
+		before := f.snapshot(k)
+		f.fields[k] = res
+		f.record("normalize", k, before)
+	}
+}
+
+func normalizeEntry(e *ConfigEntry, t Type) {
+	if parsed, err := t.Parse(unquote(e.Value)); err == nil {
+		e.Value = t.Format(parsed)
+	}
+}
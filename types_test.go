@@ -0,0 +1,246 @@
+package ggo
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestTypes(t *testing.T) {
+	tests := []struct {
+		t         Type
+		value     string
+		canonical string
+		ok        bool
+	}{
+		{TypePrefix, "198.18.1.2/24", "198.18.1.2/24", true},
+		{TypeNetwork, "198.18.1.2/24", "", false},
+		{TypeNetwork, "198.18.1.0/24", "198.18.1.0/24", true},
+		{TypePrefix, "2001:db8::/32", "2001:db8::/32", true},
+		{TypeVLAN, "106", "106", true},
+		{TypeVLAN, "0", "", false},
+		{TypeVLAN, "4095", "", false},
+		{TypeMulticastIP, "239.0.0.3", "239.0.0.3", true},
+		{TypeMulticastIP, "198.18.1.3", "", false},
+		{TypeUnicastIP, "239.0.0.3", "", false},
+		{TypeUnicastIP, "198.18.1.3", "198.18.1.3", true},
+		{TypeMAC, "EC-93-ED-01-00-00", "\"ec:93:ed:01:00:00\"", true},
+		{MACType{}, "ec:93:ed:01:00:00", "ec:93:ed:01:00:00", true},
+		{TypeMAC, "ec:93:ed:01:00", "", false},
+		{TypeRate, "6250000", "6250k", true},
+		{TypeRate, "1536", "1536", true},
+		{TypeRate, "10G", "10G", true},
+		{TypeRate, "2000k", "2M", true},
+		{TypeRate, "fast", "", false},
+		{IntType{Min: 1, Max: 8}, "9", "", false},
+	}
+
+	for _, test := range tests {
+		v, err := test.t.Parse(test.value)
+		if (err == nil) != test.ok {
+			t.Errorf("%T.Parse(%s) error %v\n", test.t, test.value, err)
+			continue
+		}
+		if err == nil && test.t.Format(v) != test.canonical {
+			t.Errorf("%T.Format(%s) = %s\n", test.t, test.value, test.t.Format(v))
+		}
+	}
+}
+
+func TestConfig_TypedGetters(t *testing.T) {
+	testData := []string{
+		"sym.prot.ipv4		198.18.1.2/24",
+		"sym.prot.vlan		106",
+		"mac		\"ec:93:ed:01:00:00\"",
+		"sync	239.0.0.3",
+		"#sflow.drop.speed	40k",
+		"pcap-pool 0",
+	}
+
+	file := NewConfig()
+	file.FromStrings(testData)
+
+	if p, err := file.GetPrefix("sym.prot.ipv4"); err != nil || p != netip.MustParsePrefix("198.18.1.2/24") {
+		t.Errorf("GetPrefix = %v, %v\n", p, err)
+	}
+	if v, err := file.GetVLAN("sym.prot.vlan"); err != nil || v != 106 {
+		t.Errorf("GetVLAN = %v, %v\n", v, err)
+	}
+	if mac, err := file.GetMAC("mac"); err != nil || mac.String() != "ec:93:ed:01:00:00" {
+		t.Errorf("GetMAC = %v, %v\n", mac, err)
+	}
+	if ip, err := file.GetIP("sync"); err != nil || ip != netip.MustParseAddr("239.0.0.3") {
+		t.Errorf("GetIP = %v, %v\n", ip, err)
+	}
+	if r, err := file.GetRate("sflow.drop.speed"); err != nil || r != 40000 {
+		t.Errorf("GetRate = %v, %v\n", r, err)
+	}
+	if n, err := file.GetInt("pcap-pool"); err != nil || n != 0 {
+		t.Errorf("GetInt = %v, %v\n", n, err)
+	}
+	if _, err := file.GetInt("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetInt on missing key returned %v\n", err)
+	}
+	if _, err := file.GetVLAN("sym.prot.ipv4"); err == nil {
+		t.Error("GetVLAN accepted a prefix")
+	}
+}
+
+func TestConfig_ValidateTypesAndNormalize(t *testing.T) {
+	file := NewConfig()
+	file.SetSchema(NewSchema(
+		&KeySpec{Name: "*.*.vlan", Type: TypeVLAN},
+		&KeySpec{Name: "sync", Multiple: true, Type: TypeMulticastIP},
+		&KeySpec{Name: "nh.mac", Type: TypeMAC},
+		&KeySpec{Name: "tb.*.speed", Type: TypeRate},
+	))
+	file.FromStrings([]string{
+		"sym.prot.vlan 5000",
+		"sync 239.0.0.3",
+		"sync 198.18.1.1",
+		"#sync 1.1.1.1",
+		"nh.mac 78-FE-3D-58-C5-4E",
+		"tb.udp.speed 20000",
+	})
+
+	err, ok := file.Validate().(*ValidationError)
+	if !ok || len(err.Violations) != 2 || err.Violations[0].Keys[0] != "sym.prot.vlan" || err.Violations[1].Keys[0] != "sync" {
+		t.Errorf("unexpected validation result %v\n", err)
+	}
+
+	file.Normalize()
+	file.checkEntry(t, true, "nh.mac", "\"78:fe:3d:58:c5:4e\"", "")
+	file.checkEntry(t, true, "tb.udp.speed", "20k", "")
+}
+
+func TestConfig_NormalizeMerge(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		file := NewConfig()
+		file.SetSchema(NewSchema(&KeySpec{Name: "tb.speed", Multiple: true, Type: TypeRate}))
+		file.FromStrings([]string{
+			"# first",
+			"tb.speed 1000",
+			"# second",
+			"tb.speed 1k",
+			"tb.speed 2000",
+		})
+		j := file.EnableJournal()
+
+		file.Normalize()
+		m := file.Get("tb.speed").(*ConfigMultiEntry)
+		if len(m.Entries) != 2 || m.Get("1k").DocComment != "second" || m.Get("2k") == nil {
+			t.Errorf("unexpected merge %v\n", m)
+			break
+		}
+		if len(j.Changes()) != 1 || j.Changes()[0].Op != "normalize" {
+			t.Errorf("changes are %+v\n", j.Changes())
+		}
+		file.Undo()
+		if v := file.Get("tb.speed").(*ConfigMultiEntry).Values(); len(v) != 3 {
+			t.Errorf("undo left %v\n", v)
+		}
+	}
+}
+
+func TestConfig_Bind(t *testing.T) {
+	type iface struct {
+		IPv4 netip.Prefix `ggo:"ipv4"`
+		VLAN uint16       `ggo:"vlan"`
+	}
+	type settings struct {
+		Prot       iface            `ggo:"sym.prot"`
+		Raw        iface            `ggo:"sym.raw"`
+		MAC        net.HardwareAddr `ggo:"mac"`
+		Sync       []netip.Addr     `ggo:"sync"`
+		Speed      uint64           `ggo:"sflow.drop.speed"`
+		Pool       int              `ggo:"sflow.drop.pool"`
+		Eth        bool             `ggo:"eth-0_1"`
+		Missing    string           `ggo:"missing"`
+		Untagged   string
+		unexported string `ggo:"mac"`
+	}
+
+	file := NewConfig()
+	file.SetSchema(NewSchema(
+		&KeySpec{Name: "sync", Multiple: true},
+		&KeySpec{Name: "sflow.*.speed", Type: TypeRate},
+		&KeySpec{Name: "sflow.*.pool"},
+	))
+	file.FromStrings([]string{
+		"sym.prot.ipv4		198.18.1.2/24",
+		"sym.prot.vlan		106",
+		"sym.raw.ipv4		198.18.0.2/24",
+		"sym.raw.vlan		103",
+		"mac		\"ec:93:ed:01:00:00\"",
+		"sync 239.1.0.3",
+		"sync 239.0.0.3",
+		"sflow.drop.speed 40k",
+		"#sflow.drop.pool 7",
+		"eth-0_1",
+	})
+
+	got := settings{Missing: "kept"}
+	if err := file.Bind(&got); err != nil {
+		t.Fatalf("Bind failed: %v\n", err)
+	}
+
+	expected := settings{
+		Prot:    iface{netip.MustParsePrefix("198.18.1.2/24"), 106},
+		Raw:     iface{netip.MustParsePrefix("198.18.0.2/24"), 103},
+		MAC:     net.HardwareAddr{0xec, 0x93, 0xed, 0x01, 0x00, 0x00},
+		Sync:    []netip.Addr{netip.MustParseAddr("239.0.0.3"), netip.MustParseAddr("239.1.0.3")},
+		Speed:   40000,
+		Pool:    7,
+		Eth:     true,
+		Missing: "kept",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Bind result %+v\n", got)
+	}
+
+	var bad struct {
+		VLAN uint8 `ggo:"sym.prot.ipv4"`
+	}
+	if err := file.Bind(&bad); err == nil {
+		t.Error("Bind accepted a prefix for an integer field")
+	}
+	if err := file.Bind(got); err == nil {
+		t.Error("Bind accepted a non-pointer")
+	}
+}
+
+func TestConfig_BindOverflow(t *testing.T) {
+	file := NewConfig()
+	file.SetSchema(NewSchema(
+		&KeySpec{Name: "mtu", Type: TypeInt},
+		&KeySpec{Name: "offset", Type: TypeInt},
+		&KeySpec{Name: "speed", Type: TypeRate},
+	))
+	file.FromStrings([]string{"mtu 9000", "offset -1", "speed 10G"})
+
+	var small struct {
+		MTU int8 `ggo:"mtu"`
+	}
+	var unsigned struct {
+		Offset uint32 `ggo:"offset"`
+	}
+	var narrow struct {
+		Speed uint16 `ggo:"speed"`
+	}
+	for _, v := range []interface{}{&small, &unsigned, &narrow} {
+		if err := file.Bind(v); err == nil {
+			t.Errorf("Bind truncated a value into %+v\n", v)
+		}
+	}
+
+	var fits struct {
+		MTU    int16  `ggo:"mtu"`
+		Offset int8   `ggo:"offset"`
+		Speed  uint64 `ggo:"speed"`
+	}
+	if err := file.Bind(&fits); err != nil || fits.MTU != 9000 || fits.Offset != -1 || fits.Speed != 10000000000 {
+		t.Errorf("Bind result %+v: %v\n", fits, err)
+	}
+}
@@ -0,0 +1,112 @@
+package ggo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// directivePrefix starts lines carrying information about the document
+// itself rather than entries.
+const directivePrefix = "#ggo:"
+
+// versionHeader starts the optional line declaring the format version of
+// a document, such as `#ggo:version 2`.
+const versionHeader = "#ggo:version"
+
+// Upgrade converts a config from format version From to From+1.
+type Upgrade struct {
+	From        int
+	Description string
+	Apply       func(c *Config) error
+}
+
+// SetVersion sets the current format version. Configs of an older
+// version are upgraded when loaded, using the registered upgrades.
+func (s *Schema) SetVersion(version int) {
+	s.version = version
+}
+
+func (s *Schema) Version() int {
+	if s == nil {
+		return 0
+	}
+	return s.version
+}
+
+// AddUpgrade registers steps between consecutive format versions.
+func (s *Schema) AddUpgrade(upgrades ...Upgrade) {
+	if s.upgrades == nil {
+		s.upgrades = make(map[int]Upgrade)
+	}
+	for _, u := range upgrades {
+		s.upgrades[u.From] = u
+	}
+}
+
+// Version returns the format version of the config: the one declared by
+// the document, raised by upgrades. Documents without a version header
+// are version 0.
+func (f *Config) Version() int {
+	return f.version
+}
+
+// parseVersionHeader reads the version from a trimmed header line.
+func parseVersionHeader(line string) (int, bool, error) {
+	if !strings.HasPrefix(line, versionHeader) {
+		return 0, false, nil
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(line[len(versionHeader):]))
+	if err != nil || version < 0 {
+		return 0, true, fmt.Errorf("invalid format version '%s'", strings.TrimSpace(line[len(versionHeader):]))
+	}
+	return version, true, nil
+}
+
+// Upgrade brings the config to the schema's format version, applying the
+// registered upgrades in order. The steps run on a copy of the config, and
+// their changes are only committed once all of them succeeded; on failure
+// the config and its Version are left as they were. Each applied step, and
+// a failure, is recorded in Diagnostics. Configs newer than the version
+// the schema declares are rejected. Configs without a schema, or older
+// ones whose schema registers no upgrades, are left at their version.
+// Loading a document upgrades it automatically.
+func (f *Config) Upgrade() error {
+	if f.schema == nil || f.schema.Version() == 0 && len(f.schema.upgrades) == 0 {
+		return nil
+	}
+	target := f.schema.Version()
+	if f.version > target {
+		err := fmt.Errorf("format version %d is newer than supported version %d", f.version, target)
+		f.diagnostics = append(f.diagnostics, Diagnostic{Line: f.versionLine, Message: err.Error()})
+		return err
+	}
+	if f.version == target || len(f.schema.upgrades) == 0 {
+		return nil
+	}
+
+	staged := f.Clone()
+	for staged.version < target {
+		u, exists := f.schema.upgrades[staged.version]
+		if !exists {
+			err := fmt.Errorf("no upgrade from format version %d", staged.version)
+			f.diagnostics = append(f.diagnostics, Diagnostic{Line: f.versionLine, Message: err.Error()})
+			return err
+		}
+		if err := u.Apply(staged); err != nil {
+			err = fmt.Errorf("upgrade from format version %d: %w", staged.version, err)
+			f.diagnostics = append(f.diagnostics, Diagnostic{Line: f.versionLine, Message: err.Error()})
+			return err
+		}
+		msg := fmt.Sprintf("upgraded from format version %d to %d", staged.version, staged.version+1)
+		if u.Description != "" {
+			msg += ": " + u.Description
+		}
+		staged.diagnostics = append(staged.diagnostics, Diagnostic{Line: f.versionLine, Message: msg})
+		staged.version++
+	}
+
+	f.diagnostics = staged.diagnostics
+	f.adopt(staged, "upgrade")
+	return nil
+}
@@ -0,0 +1,149 @@
+package ggo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func versionedSchema() *Schema {
+	s := NewSchema(&KeySpec{Name: "sflow.rate"}, &KeySpec{Name: "sflow.sampling"})
+	s.SetVersion(2)
+	s.AddUpgrade(
+		Upgrade{From: 0, Description: "rename sflow.rate", Apply: func(c *Config) error {
+			if e, ok := c.Get("sflow.rate").(*ConfigEntry); ok {
+				c.Delete("sflow.rate")
+				e.name = "sflow.sampling"
+				c.Set(e)
+			}
+			return nil
+		}},
+		Upgrade{From: 1, Apply: func(c *Config) error {
+			if e, ok := c.Get("sflow.sampling").(*ConfigEntry); ok {
+				e.Value = strings.TrimSuffix(e.Value, "k") + "000"
+			}
+			return nil
+		}},
+	)
+	return s
+}
+
+func Test_VersionUpgrade(t *testing.T) {
+	c := NewConfig()
+	c.SetSchema(versionedSchema())
+	c.FromString("sflow.rate 4k")
+
+	if c.Version() != 2 {
+		t.Errorf("Version = %d, expected 2\n", c.Version())
+	}
+	if s := c.String(); s != "#ggo:version 2\nsflow.sampling 4000" {
+		t.Errorf("String = %q\n", s)
+	}
+	doc := c.String()
+	c.checkEntry(t, true, "sflow.sampling", "4000", "")
+	if c.Get("sflow.rate") != nil {
+		t.Errorf("sflow.rate was not renamed\n")
+	}
+	d := c.Diagnostics()
+	if len(d) != 2 || d[0].Message != "upgraded from format version 0 to 1: rename sflow.rate" ||
+		d[1].Message != "upgraded from format version 1 to 2" {
+		t.Errorf("unexpected report %v\n", d)
+	}
+
+	// A current document is read as it is.
+	c.FromString(doc)
+	c.checkEntry(t, true, "sflow.sampling", "4000", "")
+	if c.Version() != 2 || len(c.Diagnostics()) != 0 {
+		t.Errorf("current document was upgraded: %d %v\n", c.Version(), c.Diagnostics())
+	}
+}
+
+func Test_VersionUpgradeFailure(t *testing.T) {
+	s := versionedSchema()
+	c := NewConfig()
+	c.SetSchema(s)
+	c.FromString("#ggo:version 3\nsflow.sampling 1")
+	if c.Version() != 3 || len(c.Diagnostics()) != 1 || c.Diagnostics()[0].Line != 1 {
+		t.Errorf("newer version not reported: %v\n", c.Diagnostics())
+	}
+
+	failure := errors.New("boom")
+	s.AddUpgrade(Upgrade{From: 1, Apply: func(c *Config) error { return failure }})
+	if err := c.FromString("#ggo:version 1\nsflow.sampling 1"); !errors.Is(err, failure) {
+		t.Errorf("FromString = %v\n", err)
+	}
+	if err := c.Upgrade(); !errors.Is(err, failure) {
+		t.Errorf("Upgrade = %v\n", err)
+	}
+	if c.Version() != 1 {
+		t.Errorf("Version = %d after failed upgrade\n", c.Version())
+	}
+
+	// The first step succeeds, the second fails: nothing is committed.
+	c.FromString("sflow.rate 4k")
+	if c.Version() != 0 || c.Get("sflow.sampling") != nil {
+		t.Errorf("partial upgrade committed: version %d\n%s\n", c.Version(), c)
+	}
+	c.checkEntry(t, true, "sflow.rate", "4k", "")
+}
+
+func Test_VersionWithoutUpgrades(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ggo.conf")
+	if err := os.WriteFile(path, []byte("#ggo:version 1\nkey value\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	c := NewConfig()
+	if err := c.FromFile(file); err != nil {
+		t.Errorf("FromFile without schema: %v\n", err)
+	}
+	if c.Version() != 1 || len(c.Diagnostics()) != 0 {
+		t.Errorf("version %d, %v\n", c.Version(), c.Diagnostics())
+	}
+
+	s := NewSchema(&KeySpec{Name: "key"})
+	c = NewConfig()
+	c.SetSchema(s)
+	if err := c.FromString("#ggo:version 3\nkey value"); err != nil {
+		t.Errorf("schema without upgrades: %v\n", err)
+	}
+
+	s.SetVersion(2)
+	if err := c.FromString("#ggo:version 1\nkey value"); err != nil || c.Version() != 1 {
+		t.Errorf("older version with no upgrades: %d, %v\n", c.Version(), err)
+	}
+	if err := c.FromString("#ggo:version 9\nkey value"); err == nil {
+		t.Errorf("newer version accepted by a schema without upgrades\n")
+	}
+}
+
+func Test_VersionHeader(t *testing.T) {
+	c := NewConfig()
+	c.FromString("#ggo:version 1\nkey value")
+	if c.Version() != 1 || c.Get("ggo:version") != nil {
+		t.Errorf("header read as entry\n")
+	}
+
+	c.FromString("#ggo:version x")
+	if d := c.Diagnostics(); len(d) == 0 || d[0].Message != "invalid format version 'x'" {
+		t.Errorf("invalid header not reported: %v\n", d)
+	}
+
+	c = NewConfig()
+	c.FromString("key value")
+	if s := c.String(); s != "key value" {
+		t.Errorf("unversioned config written as %q\n", s)
+	}
+
+	out, err := Format([]byte("#ggo:version 2\nkey  value\n"))
+	if err != nil || string(out) != "#ggo:version 2\nkey value\n" {
+		t.Errorf("Format = %q, %v\n", out, err)
+	}
+}